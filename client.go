@@ -196,12 +196,12 @@ func (r *Response) UnmarshalIncluded(resourceType string, target interface{}, op
 	}
 
 	if target == nil {
-		return fmt.Errorf("target must not be nil")
+		return fmt.Errorf("%w: target must not be nil", ErrNilValue)
 	}
 
 	targetVal := reflect.ValueOf(target)
 	if targetVal.Kind() != reflect.Ptr {
-		return fmt.Errorf("target must be a ptr")
+		return fmt.Errorf("%w: target must be a ptr", ErrNotPointer)
 	}
 
 	targetSlice := targetVal.Elem()
@@ -59,7 +59,7 @@ func (a Article) MarshalRef(name string) []jsonapi.ResourceIdentifier {
 	return nil
 }
 
-func (a Article) RelationLinks(name string) map[string]jsonapi.Link {
+func (a Article) MarshalRefLinks(name string) map[string]jsonapi.Link {
 	switch name {
 	case "comments":
 		return map[string]jsonapi.Link{
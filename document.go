@@ -7,6 +7,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
 )
 
 // MarshalFunc defines the signature for JSON marshaling functions.
@@ -18,12 +22,15 @@ type UnmarshalFunc = func(data []byte, v interface{}) error
 var (
 	jsonMarshal   MarshalFunc   = json.Marshal   // Default JSON marshaler
 	jsonUnmarshal UnmarshalFunc = json.Unmarshal // Default JSON unmarshaler
+
+	jsonMarshalSet bool // Whether SetJSONMarshaler replaced the default JSON marshaler
 )
 
 // SetJSONMarshaler replaces the default JSON marshaling function with a custom implementation.
 // This allows users to integrate custom JSON libraries or add preprocessing logic.
 func SetJSONMarshaler(m MarshalFunc) {
 	jsonMarshal = m
+	jsonMarshalSet = true
 }
 
 // SetJSONUnmarshaler replaces the default JSON unmarshaling function with a custom implementation.
@@ -32,14 +39,159 @@ func SetJSONUnmarshaler(u UnmarshalFunc) {
 	jsonUnmarshal = u
 }
 
+// Version is the JSON:API specification version emitted in the top-level
+// jsonapi member of marshaled documents.
+const Version = "1.1"
+
 // Document represents the top-level JSON:API document structure as defined in the specification.
 // It contains the primary data, metadata, links, errors, and included resources.
 type Document struct {
+	JSONAPI  *JSONAPIObject         `json:"jsonapi,omitempty"`  // Top-level jsonapi object
 	Links    map[string]Link        `json:"links,omitempty"`    // Top-level links object
 	Meta     map[string]interface{} `json:"meta,omitempty"`     // Top-level meta information
 	Errors   []*Error               `json:"errors,omitempty"`   // Array of error objects
 	Data     *DocumentData          `json:"data,omitempty"`     // Primary data for the document
 	Included []*Resource            `json:"included,omitempty"` // Array of included resource objects
+
+	// Extensions holds additional top-level members, such as those defined by JSON:API
+	// extensions (e.g. "atomic:results"). They are serialized at the document root alongside
+	// the standard members; unmarshaling collects any non-standard top-level members here.
+	Extensions map[string]json.RawMessage `json:"-"`
+}
+
+// documentMembers lists the top-level members defined by the specification, which
+// cannot be set or overridden through [Document.Extensions].
+var documentMembers = map[string]bool{
+	"jsonapi":  true,
+	"links":    true,
+	"meta":     true,
+	"errors":   true,
+	"data":     true,
+	"included": true,
+}
+
+// MarshalJSON implements the [json.Marshaler] interface for [Document]. Members in
+// [Document.Extensions] are appended to the standard members in sorted key order.
+func (d Document) MarshalJSON() ([]byte, error) {
+	type document Document
+	data, err := jsonMarshal(document(d))
+	if err != nil || len(d.Extensions) == 0 {
+		return data, err
+	}
+
+	keys := make([]string, 0, len(d.Extensions))
+	for key := range d.Extensions {
+		if documentMembers[key] {
+			return nil, fmt.Errorf("extension member %q conflicts with a standard document member", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	members := bytes.TrimSuffix(bytes.TrimSpace(data), []byte("}"))
+	buf.Write(members)
+	for i, key := range keys {
+		if i > 0 || len(bytes.TrimSpace(members)) > 1 {
+			buf.WriteByte(',')
+		}
+		name, err := jsonMarshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value := d.Extensions[key]
+		if len(value) == 0 {
+			value = json.RawMessage("null")
+		}
+		if !json.Valid(value) {
+			return nil, fmt.Errorf("extension member %q: invalid JSON value", key)
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface for [Document]. Top-level
+// members not defined by the specification are collected into [Document.Extensions].
+func (d *Document) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := jsonUnmarshal(data, &members); err != nil {
+		return err
+	}
+
+	var doc Document
+	fields := []struct {
+		name   string
+		target interface{}
+	}{
+		{"jsonapi", &doc.JSONAPI},
+		{"links", &doc.Links},
+		{"meta", &doc.Meta},
+		{"errors", &doc.Errors},
+		{"data", &doc.Data},
+		{"included", &doc.Included},
+	}
+	for _, field := range fields {
+		if value, ok := members[field.name]; ok {
+			if err := jsonUnmarshal(value, field.target); err != nil {
+				return err
+			}
+		}
+	}
+
+	for key, value := range members {
+		if documentMembers[key] {
+			continue
+		}
+		if doc.Extensions == nil {
+			doc.Extensions = make(map[string]json.RawMessage)
+		}
+		doc.Extensions[key] = value
+	}
+
+	*d = doc
+	return nil
+}
+
+// ToMap converts the document into its generic map representation, as produced by
+// decoding the marshaled document into a map[string]interface{}. This is useful for
+// tooling that inspects or modifies documents without the full structs.
+func (d Document) ToMap() (map[string]interface{}, error) {
+	data, err := jsonMarshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	if err := jsonUnmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DocumentFromMap converts a generic map representation of a JSON:API document,
+// such as one returned by [Document.ToMap], back into a [Document].
+func DocumentFromMap(m map[string]interface{}) (*Document, error) {
+	data, err := jsonMarshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc Document
+	if err := jsonUnmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// JSONAPIObject represents the top-level jsonapi member of a [Document],
+// describing the server's implementation of the specification.
+type JSONAPIObject struct {
+	Version string                 `json:"version,omitempty"` // Highest specification version supported
+	Meta    map[string]interface{} `json:"meta,omitempty"`    // Implementation-specific metadata
 }
 
 // DocumentData represents the primary data of a JSON:API [Document].
@@ -89,11 +241,121 @@ func (d *DocumentData) UnmarshalJSON(data []byte) error {
 // attributes, relationships, links, and metadata.
 type Resource struct {
 	ID            string                   `json:"id,omitempty"`            // Unique identifier for the resource
+	LID           string                   `json:"lid,omitempty"`           // Local identifier for a resource not yet created
 	Type          string                   `json:"type,omitempty"`          // Resource type identifier
 	Attributes    json.RawMessage          `json:"attributes,omitempty"`    // Resource attributes as raw JSON
 	Relationships map[string]*Relationship `json:"relationships,omitempty"` // Related resources
 	Links         map[string]Link          `json:"links,omitempty"`         // Resource-specific links
 	Meta          map[string]interface{}   `json:"meta,omitempty"`          // Resource-specific metadata
+
+	emptyRelationships bool // Whether an empty relationships object is emitted
+}
+
+// MarshalJSON implements the [json.Marshaler] interface for [Resource]. The relationships
+// member is omitted when empty, unless the resource was marshaled with
+// [WithEmptyRelationshipsObject].
+func (r Resource) MarshalJSON() ([]byte, error) {
+	type resource Resource
+	if !r.emptyRelationships || len(r.Relationships) > 0 {
+		return jsonMarshal(resource(r))
+	}
+	return jsonMarshal(struct {
+		resource
+		Relationships map[string]*Relationship `json:"relationships"`
+	}{resource(r), map[string]*Relationship{}})
+}
+
+// Validate reports whether the resource is correctly identified, requiring a non-empty
+// Type and either an ID or a LID. Resources embedded in a document's included member must
+// pass this check; resources sent by a client for creation may omit both identifiers and
+// should not be validated with it.
+func (r Resource) Validate() error {
+	if r.Type == "" {
+		return fmt.Errorf("resource %q: missing type", r.ID)
+	}
+	if r.ID == "" && r.LID == "" {
+		return fmt.Errorf("resource of type %q: missing id", r.Type)
+	}
+	return nil
+}
+
+// DiffResources returns a resource identified like newer that contains only the attributes
+// and relationships that differ from older, suitable as the body of a minimal PATCH request.
+// Values are compared by their JSON meaning, so formatting and key order are ignored.
+// Attributes removed in newer are set to null. If either attributes object cannot be
+// decoded, all attributes of newer are kept.
+//
+// Example:
+//
+//	patch := jsonapi.DiffResources(original, edited)
+//	body, err := json.Marshal(map[string]interface{}{"data": patch})
+func DiffResources(older, newer Resource) Resource {
+	diff := Resource{ID: newer.ID, LID: newer.LID, Type: newer.Type}
+	diff.Attributes = diffAttributes(older.Attributes, newer.Attributes)
+
+	for name, rel := range newer.Relationships {
+		if !jsonEqual(older.Relationships[name], rel) {
+			if diff.Relationships == nil {
+				diff.Relationships = make(map[string]*Relationship)
+			}
+			diff.Relationships[name] = rel
+		}
+	}
+	return diff
+}
+
+// diffAttributes returns the attributes object of members in newer that differ from older.
+func diffAttributes(older, newer json.RawMessage) json.RawMessage {
+	var before, after map[string]json.RawMessage
+	if len(older) > 0 {
+		if err := jsonUnmarshal(older, &before); err != nil {
+			return newer
+		}
+	}
+	if len(newer) > 0 {
+		if err := jsonUnmarshal(newer, &after); err != nil {
+			return newer
+		}
+	}
+
+	changed := make(map[string]json.RawMessage)
+	for key, value := range after {
+		if previous, ok := before[key]; !ok || !jsonEqual(previous, value) {
+			changed[key] = value
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			changed[key] = json.RawMessage("null")
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+	data, err := jsonMarshal(changed)
+	if err != nil {
+		return newer
+	}
+	return data
+}
+
+// jsonEqual reports whether a and b have the same JSON representation once decoded.
+func jsonEqual(a, b interface{}) bool {
+	decode := func(v interface{}) (interface{}, bool) {
+		data, err := jsonMarshal(v)
+		if err != nil {
+			return nil, false
+		}
+		var out interface{}
+		if err := jsonUnmarshal(data, &out); err != nil {
+			return nil, false
+		}
+		return out, true
+	}
+	x, okA := decode(a)
+	y, okB := decode(b)
+	return okA && okB && reflect.DeepEqual(x, y)
 }
 
 // Relationship represents a JSON:API relationship object that describes
@@ -104,6 +366,14 @@ type Relationship struct {
 	Meta  map[string]interface{} `json:"meta,omitempty"`  // Relationship-specific metadata
 }
 
+// isEmpty reports whether the relationship has no links, no meta, and null or absent resource linkage.
+func (r Relationship) isEmpty() bool {
+	if len(r.Links) > 0 || len(r.Meta) > 0 {
+		return false
+	}
+	return r.Data == nil || (!r.Data.isMany && r.Data.one.ID == "")
+}
+
 func (r Relationship) hoistToPrimary(doc *Document) {
 	doc.Links = r.Links
 	doc.Meta = r.Meta
@@ -121,6 +391,24 @@ type RelationshipData struct {
 	isMany bool  // Flag indicating if relationship contains multiple references
 }
 
+// ToOneData creates to-one [RelationshipData] referencing the provided resource.
+// A nil reference produces null resource linkage.
+func ToOneData(ref ResourceIdentifier) *RelationshipData {
+	if ref == nil {
+		return &RelationshipData{}
+	}
+	return &RelationshipData{one: Ref{Type: ref.ResourceType(), ID: ref.ResourceID()}}
+}
+
+// ToManyData creates to-many [RelationshipData] referencing the provided resources.
+func ToManyData(refs ...ResourceIdentifier) *RelationshipData {
+	data := &RelationshipData{isMany: true, many: make([]Ref, 0, len(refs))}
+	for _, ref := range refs {
+		data.many = append(data.many, Ref{Type: ref.ResourceType(), ID: ref.ResourceID()})
+	}
+	return data
+}
+
 func (r RelationshipData) hoistToPrimary(doc *Document) {
 	doc.Data = &DocumentData{}
 	if r.isMany {
@@ -191,6 +479,7 @@ type Error struct {
 	Title  string                 `json:"title,omitempty"`  // Short, human-readable summary
 	Detail string                 `json:"detail,omitempty"` // Human-readable explanation
 	Source ErrorSource            `json:"source,omitempty"` // Object containing references to error source
+	Links  map[string]Link        `json:"links,omitempty"`  // Links to further details, such as "about" and "type"
 	Meta   map[string]interface{} `json:"meta,omitempty"`   // Error-specific metadata
 }
 
@@ -204,11 +493,22 @@ func (e *Error) Error() string {
 	return e.Detail
 }
 
+// UnsupportedMediaType creates a JSON:API [Error] for a 415 Unsupported Media Type response,
+// used when a request body is sent with a Content-Type other than the JSON:API media type.
+func UnsupportedMediaType(detail string) Error {
+	return Error{
+		Status: strconv.Itoa(http.StatusUnsupportedMediaType),
+		Title:  http.StatusText(http.StatusUnsupportedMediaType),
+		Detail: detail,
+	}
+}
+
 // ErrorSource represents the source of an [Error], indicating where in the request
 // [Document] or parameter the error originated.
 type ErrorSource struct {
 	Pointer   string `json:"pointer,omitempty"`   // JSON Pointer to the associated entity in request document
 	Parameter string `json:"parameter,omitempty"` // String indicating which URI query parameter caused error
+	Header    string `json:"header,omitempty"`    // Name of the request header that caused error
 }
 
 // Link represents a JSON:API link object that can be either a simple URL string
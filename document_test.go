@@ -2,20 +2,24 @@ package jsonapi
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSetJSONMarshaler(t *testing.T) {
 	originalMarshaler := jsonMarshal
-	defer func() { jsonMarshal = originalMarshaler }()
+	defer func() { jsonMarshal, jsonMarshalSet = originalMarshaler, false }()
 
 	customMarshaler := json.Marshal
 	SetJSONMarshaler(customMarshaler)
 
 	// Verify the marshaler was set (we can't directly compare functions)
 	assert.NotNil(t, jsonMarshal)
+	assert.True(t, jsonMarshalSet)
 }
 
 func TestSetJSONUnmarshaler(t *testing.T) {
@@ -147,3 +151,177 @@ func TestDocumentData_MarshalJSON_NullWhenEmpty(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "null", string(b))
 }
+
+func TestUnsupportedMediaType(t *testing.T) {
+	err := UnsupportedMediaType("Content-Type must be application/vnd.api+json")
+	assert.Equal(t, "415", err.Status)
+	assert.Equal(t, "Unsupported Media Type", err.Title)
+	assert.Equal(t, "Content-Type must be application/vnd.api+json", err.Detail)
+
+	w := httptest.NewRecorder()
+	_, werr := (&Context{}).MarshalErrors(w, http.StatusUnsupportedMediaType, &err)
+	assert.NoError(t, werr)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	var doc Document
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	if assert.Len(t, doc.Errors, 1) {
+		assert.Equal(t, "415", doc.Errors[0].Status)
+		assert.Equal(t, "Unsupported Media Type", doc.Errors[0].Title)
+		assert.Equal(t, "Content-Type must be application/vnd.api+json", doc.Errors[0].Detail)
+	}
+}
+
+func TestResource_Validate(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		assert.NoError(t, Resource{ID: "1", Type: "users"}.Validate())
+		assert.NoError(t, Resource{LID: "temp-1", Type: "users"}.Validate())
+	})
+
+	t.Run("missing type", func(t *testing.T) {
+		err := Resource{ID: "1"}.Validate()
+		assert.EqualError(t, err, `resource "1": missing type`)
+	})
+
+	t.Run("missing id", func(t *testing.T) {
+		err := Resource{Type: "users"}.Validate()
+		assert.EqualError(t, err, `resource of type "users": missing id`)
+	})
+}
+
+func TestDocument_ToMap(t *testing.T) {
+	data, err := Marshal(Article{ID: "1", Title: "Hello", AuthorID: "1"}, WithTopMeta("total", 1))
+	require.NoError(t, err)
+
+	var doc Document
+	require.NoError(t, json.Unmarshal(data, &doc))
+
+	m, err := doc.ToMap()
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), m["meta"].(map[string]interface{})["total"])
+
+	primary := m["data"].(map[string]interface{})
+	assert.Equal(t, "articles", primary["type"])
+	primary["attributes"].(map[string]interface{})["title"] = "Goodbye"
+
+	roundTrip, err := DocumentFromMap(m)
+	require.NoError(t, err)
+	if assert.Len(t, roundTrip.Included, 1) {
+		assert.Equal(t, "users", roundTrip.Included[0].Type)
+		assert.JSONEq(t, string(doc.Included[0].Attributes), string(roundTrip.Included[0].Attributes))
+	}
+	assert.Equal(t, doc.JSONAPI, roundTrip.JSONAPI)
+
+	var article Article
+	require.NoError(t, Unmarshal(mustMarshal(t, roundTrip), &article))
+	assert.Equal(t, "Goodbye", article.Title)
+	assert.Equal(t, "1", article.AuthorID)
+}
+
+func TestDocument_Extensions(t *testing.T) {
+	t.Run("emits extension members at the root", func(t *testing.T) {
+		doc := Document{
+			Meta: map[string]interface{}{"total": 1},
+			Extensions: map[string]json.RawMessage{
+				"atomic:results": json.RawMessage(`[{"data":{"type":"articles","id":"1"}}]`),
+			},
+		}
+
+		assert.JSONEq(t, `{
+			"meta": {"total": 1},
+			"atomic:results": [{"data": {"type": "articles", "id": "1"}}]
+		}`, string(mustMarshal(t, doc)))
+	})
+
+	t.Run("only extension members", func(t *testing.T) {
+		doc := Document{Extensions: map[string]json.RawMessage{
+			"b:two": json.RawMessage(`2`),
+			"a:one": json.RawMessage(`1`),
+		}}
+		assert.Equal(t, `{"a:one":1,"b:two":2}`, string(mustMarshal(t, doc)))
+	})
+
+	t.Run("rejects standard member names", func(t *testing.T) {
+		doc := Document{Extensions: map[string]json.RawMessage{"data": json.RawMessage(`null`)}}
+		_, err := json.Marshal(doc)
+		assert.ErrorContains(t, err, `extension member "data" conflicts with a standard document member`)
+	})
+
+	t.Run("rejects invalid JSON values", func(t *testing.T) {
+		doc := Document{Extensions: map[string]json.RawMessage{"ext:bad": json.RawMessage(`{`)}}
+		_, err := json.Marshal(doc)
+		assert.ErrorContains(t, err, `extension member "ext:bad": invalid JSON value`)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		var doc Document
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"data": {"type": "articles", "id": "1"},
+			"atomic:results": [{}]
+		}`), &doc))
+
+		require.Len(t, doc.Extensions, 1)
+		assert.JSONEq(t, `[{}]`, string(doc.Extensions["atomic:results"]))
+		require.NotNil(t, doc.Data)
+
+		m, err := doc.ToMap()
+		require.NoError(t, err)
+		assert.Contains(t, m, "atomic:results")
+		assert.Contains(t, m, "data")
+	})
+
+	t.Run("no extensions", func(t *testing.T) {
+		var doc Document
+		require.NoError(t, json.Unmarshal([]byte(`{"meta": {"total": 1}}`), &doc))
+		assert.Nil(t, doc.Extensions)
+	})
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
+
+func TestDiffResources(t *testing.T) {
+	older := Resource{
+		ID:         "1",
+		Type:       "articles",
+		Attributes: json.RawMessage(`{"title":"Draft","content":"Body","tags":["a","b"],"subtitle":"Old"}`),
+		Relationships: map[string]*Relationship{
+			"author": {Data: &RelationshipData{one: Ref{ID: "1", Type: "users"}}},
+			"editor": {Data: &RelationshipData{one: Ref{ID: "2", Type: "users"}}},
+		},
+	}
+	newer := Resource{
+		ID:         "1",
+		Type:       "articles",
+		Attributes: json.RawMessage(`{ "tags": ["a", "b"], "content": "Body", "title": "Published" }`),
+		Relationships: map[string]*Relationship{
+			"author": {Data: &RelationshipData{one: Ref{ID: "1", Type: "users"}}},
+			"editor": {Data: &RelationshipData{one: Ref{ID: "3", Type: "users"}}},
+		},
+	}
+
+	t.Run("minimal patch", func(t *testing.T) {
+		diff := DiffResources(older, newer)
+		assert.Equal(t, "1", diff.ID)
+		assert.Equal(t, "articles", diff.Type)
+		assert.JSONEq(t, `{"title":"Published","subtitle":null}`, string(diff.Attributes))
+		if assert.Len(t, diff.Relationships, 1) {
+			assert.Equal(t, "3", diff.Relationships["editor"].Data.one.ID)
+		}
+	})
+
+	t.Run("identical resources", func(t *testing.T) {
+		diff := DiffResources(newer, newer)
+		assert.Equal(t, Resource{ID: "1", Type: "articles"}, diff)
+	})
+
+	t.Run("new resource", func(t *testing.T) {
+		diff := DiffResources(Resource{}, newer)
+		assert.JSONEq(t, string(newer.Attributes), string(diff.Attributes))
+		assert.Len(t, diff.Relationships, 2)
+	})
+}
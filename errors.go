@@ -0,0 +1,333 @@
+package jsonapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrReadOnly may be returned by [RelationshipUnmarshaler] implementations to reject a
+// client write to a read-only relationship. [Unmarshal] reports it alongside a 403 Forbidden
+// [Error] whose source pointer references the offending relationship, so both
+// errors.Is(err, ErrReadOnly) and errors.As(err, &jsonapiErr) hold for the returned error.
+var ErrReadOnly = errors.New("read-only")
+
+// ErrTypeMismatch is reported by [Unmarshal] when type validation is enabled and a
+// resource's type does not match the type of the target.
+var ErrTypeMismatch = errors.New("resource type mismatch")
+
+// ErrNilValue is reported by [Unmarshal] and related functions when the target is nil.
+var ErrNilValue = errors.New("nil value")
+
+// ErrNotPointer is reported by [Unmarshal] and related functions when the target is not a pointer.
+var ErrNotPointer = errors.New("not a pointer")
+
+// ErrInvalidTag is reported by [Unmarshal] when a field carrying a `jsonapi` struct tag,
+// such as `jsonapi:"links"`, has a type that cannot hold the tagged member.
+var ErrInvalidTag = errors.New("invalid jsonapi tag")
+
+// ErrEmptyBody is reported by [Unmarshal] when the document has no primary data to unmarshal.
+// The returned error also matches [io.EOF].
+var ErrEmptyBody = errors.New("no data to unmarshal")
+
+// StatusForError returns the HTTP status code appropriate for err. The status of a
+// JSON:API [Error] found in the error chain is used when present; otherwise sentinel
+// errors are mapped as follows, and any other error maps to 500 Internal Server Error:
+//
+//   - [ErrReadOnly]: 403 Forbidden
+//   - [ErrTypeMismatch]: 409 Conflict
+//   - [ErrEmptyBody]: 400 Bad Request
+func StatusForError(err error) int {
+	var jsonErr *Error
+	if errors.As(err, &jsonErr) {
+		if status, convErr := strconv.Atoi(jsonErr.Status); convErr == nil {
+			return status
+		}
+	}
+
+	switch {
+	case errors.Is(err, ErrReadOnly):
+		return http.StatusForbidden
+	case errors.Is(err, ErrTypeMismatch):
+		return http.StatusConflict
+	case errors.Is(err, ErrEmptyBody):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// attributeError creates a 422 Unprocessable Entity [Error] for an invalid attribute
+// at the JSON Pointer.
+func attributeError(pointer, detail string) *Error {
+	return &Error{
+		Status: strconv.Itoa(http.StatusUnprocessableEntity),
+		Title:  http.StatusText(http.StatusUnprocessableEntity),
+		Detail: detail,
+		Source: ErrorSource{Pointer: pointer},
+	}
+}
+
+// readOnlyError wraps an [ErrReadOnly] failure with a 403 Forbidden [Error] pointing at
+// the relationship that triggered it, whose linkage is found at pointer.
+func readOnlyError(name, pointer string, err error) error {
+	source := &Error{
+		Status: strconv.Itoa(http.StatusForbidden),
+		Title:  http.StatusText(http.StatusForbidden),
+		Detail: fmt.Sprintf("relationship %q is read-only", name),
+		Source: ErrorSource{Pointer: pointer},
+	}
+	return fmt.Errorf("%w: %w", source, err)
+}
+
+// ErrorFromProblemJSON converts an RFC 7807 problem details document (application/problem+json)
+// into a JSON:API [Error]. The title, detail, and status members are carried over directly,
+// the type and instance URIs become the "type" and "about" error links, and any extension
+// members are preserved in the error's meta.
+//
+// Example:
+//
+//	if resp.Header.Get("Content-Type") == "application/problem+json" {
+//		jsonapiErr, err := jsonapi.ErrorFromProblemJSON(body)
+//		...
+//	}
+func ErrorFromProblemJSON(data []byte) (Error, error) {
+	var problem map[string]interface{}
+	if err := jsonUnmarshal(data, &problem); err != nil {
+		return Error{}, fmt.Errorf("unmarshal problem details: %w", err)
+	}
+
+	var result Error
+	for key, value := range problem {
+		switch key {
+		case "title":
+			result.Title, _ = value.(string)
+		case "detail":
+			result.Detail, _ = value.(string)
+		case "status":
+			if status, ok := value.(float64); ok {
+				result.Status = strconv.Itoa(int(status))
+			}
+		case "type", "instance":
+			href, _ := value.(string)
+			if href == "" || href == "about:blank" {
+				continue
+			}
+			if result.Links == nil {
+				result.Links = make(map[string]Link)
+			}
+			if key == "type" {
+				result.Links["type"] = Link{Href: href}
+			} else {
+				result.Links["about"] = Link{Href: href}
+			}
+		default:
+			if result.Meta == nil {
+				result.Meta = make(map[string]interface{})
+			}
+			result.Meta[key] = value
+		}
+	}
+	return result, nil
+}
+
+// MultiError is a collection of JSON:API [Error] objects that can be returned
+// as a single Go error. When passed to [WithError] or [Context.MarshalErrors],
+// each contained error becomes a separate entry in the document's errors array.
+type MultiError []*Error
+
+// Error implements the error interface by joining the messages of all contained errors.
+func (m MultiError) Error() string {
+	messages := make([]string, len(m))
+	for i, err := range m {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the contained errors so they can be inspected with [errors.Is] and [errors.As].
+func (m MultiError) Unwrap() []error {
+	errs := make([]error, len(m))
+	for i, err := range m {
+		errs[i] = err
+	}
+	return errs
+}
+
+// StampID returns a copy of the errors with their ID set to id, such as a request or
+// correlation ID shared by every error of a response. Errors that already have an ID
+// keep it. The original errors are not modified.
+//
+// Example:
+//
+//	errs = errs.StampID(r.Header.Get("X-Request-ID"))
+//	ctx.MarshalErrors(w, http.StatusUnprocessableEntity, errs)
+func (m MultiError) StampID(id string) MultiError {
+	errs := make(MultiError, len(m))
+	for i, err := range m {
+		copied := *err
+		if copied.ID == "" {
+			copied.ID = id
+		}
+		errs[i] = &copied
+	}
+	return errs
+}
+
+// withPointerPrefix returns a copy of the errors whose source pointers starting with
+// prefix are rewritten to start with replacement instead.
+func (m MultiError) withPointerPrefix(prefix, replacement string) MultiError {
+	errs := make(MultiError, len(m))
+	for i, err := range m {
+		copied := *err
+		if pointer, ok := strings.CutPrefix(copied.Source.Pointer, prefix); ok {
+			copied.Source.Pointer = replacement + pointer
+		}
+		errs[i] = &copied
+	}
+	return errs
+}
+
+// ErrorsFromFieldMap converts validation messages keyed by Go struct field name into a
+// [MultiError] of 422 Unprocessable Entity errors whose source pointers reference the
+// corresponding attributes. This is useful for adapting the output of struct validators.
+//
+// Without a resource to inspect, field names are mapped to attribute names by lowering
+// their leading initialism, so Name maps to /data/attributes/name and CreatedAt to
+// /data/attributes/createdAt. Use [ErrorsFromStructFields] when the attribute names come
+// from json struct tags. Errors are sorted by source pointer for deterministic output.
+//
+// Example:
+//
+//	errs := jsonapi.ErrorsFromFieldMap(map[string]string{
+//		"Title": "title is required",
+//	})
+//	ctx.MarshalErrors(w, http.StatusUnprocessableEntity, errs)
+func ErrorsFromFieldMap(m map[string]string) MultiError {
+	return errorsFromFields(m, func(field string) string {
+		return "/data/attributes/" + lowerInitial(field)
+	})
+}
+
+// ErrorsFromStructFields is like [ErrorsFromFieldMap], but resolves attribute names from
+// the json struct tags of the resource v, so a field Name tagged `json:"name"` maps to the
+// pointer /data/attributes/name. Fields that cannot be resolved, or a nil resource, fall
+// back to the field name as given, and fields that are not attributes, such as those
+// tagged json:"-", produce errors without a source pointer. See [PointerForField].
+//
+// Example:
+//
+//	errs := jsonapi.ErrorsFromStructFields(article, map[string]string{
+//		"Title": "title is required",
+//	})
+//	ctx.MarshalErrors(w, http.StatusUnprocessableEntity, errs)
+func ErrorsFromStructFields(v interface{}, fields map[string]string) MultiError {
+	return errorsFromFields(fields, func(field string) string {
+		return PointerForField(v, field)
+	})
+}
+
+// errorsFromFields builds one attribute error per field, sorted by source pointer.
+func errorsFromFields(fields map[string]string, pointer func(field string) string) MultiError {
+	errs := make(MultiError, 0, len(fields))
+	for field, detail := range fields {
+		errs = append(errs, attributeError(pointer(field), detail))
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Source.Pointer < errs[j].Source.Pointer
+	})
+	return errs
+}
+
+// lowerInitial lowers the leading upper case run of a Go identifier, keeping the last
+// letter of the run when it starts the next word, so ID becomes id and URLPath urlPath.
+func lowerInitial(name string) string {
+	runes := []rune(name)
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// ErrorSourcePointer returns an [ErrorSource] referencing the request document member
+// at the JSON Pointer p, such as "/data/attributes/title".
+func ErrorSourcePointer(p string) ErrorSource {
+	return ErrorSource{Pointer: p}
+}
+
+// ErrorSourceParameter returns an [ErrorSource] referencing the URI query parameter p.
+func ErrorSourceParameter(p string) ErrorSource {
+	return ErrorSource{Parameter: p}
+}
+
+// ErrorSourceHeader returns an [ErrorSource] referencing the request header h.
+func ErrorSourceHeader(h string) ErrorSource {
+	return ErrorSource{Header: h}
+}
+
+// WithSource sets the source of the error and returns e, so that it can be chained
+// when building an [Error].
+//
+// Example:
+//
+//	err := (&jsonapi.Error{Status: "400", Detail: "unknown field"}).
+//		WithSource(jsonapi.ErrorSourceParameter("fields[articles]"))
+func (e *Error) WithSource(src ErrorSource) *Error {
+	e.Source = src
+	return e
+}
+
+// PointerForField returns the JSON Pointer of the attribute of resource v backed by the Go
+// struct field goFieldName, for use as an [ErrorSource] pointer. The attribute name is
+// resolved from the field's json struct tag, including fields promoted from embedded
+// structs, and falls back to the field name as given when v has no such field. It returns
+// an empty string for fields that are not marshaled as attributes, such as fields tagged
+// json:"-".
+//
+// Example:
+//
+//	jsonapi.PointerForField(Article{}, "Title") // "/data/attributes/title"
+func PointerForField(v interface{}, goFieldName string) string {
+	name, ok := attributeName(v, goFieldName)
+	if !ok {
+		return ""
+	}
+	return "/data/attributes/" + name
+}
+
+// attributeName resolves the JSON:API attribute name of the Go struct field on v, falling
+// back to the field name if v has no such field. It reports false if the field exists but
+// is not marshaled as an attribute.
+func attributeName(v interface{}, field string) (string, bool) {
+	if v == nil {
+		return field, true
+	}
+
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return field, true
+	}
+
+	structField, ok := t.FieldByName(field)
+	if !ok {
+		return field, true
+	}
+	for _, attr := range attributeFields(t) {
+		if slices.Equal(attr.index, structField.Index) {
+			return attr.name, true
+		}
+	}
+	return "", false
+}
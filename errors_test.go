@@ -0,0 +1,325 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiError(t *testing.T) {
+	first := &Error{Title: "Invalid", Detail: "first"}
+	second := &Error{Title: "Invalid", Detail: "second"}
+	errs := MultiError{first, second}
+
+	assert.Equal(t, "Invalid: first; Invalid: second", errs.Error())
+	assert.True(t, errors.Is(errs, second))
+
+	wrapped := fmt.Errorf("validate: %w", errs)
+	var multiErr MultiError
+	require.True(t, errors.As(wrapped, &multiErr))
+	assert.Len(t, multiErr, 2)
+}
+
+func TestMultiError_StampID(t *testing.T) {
+	errs := MultiError{
+		{Status: "422", Detail: "first"},
+		{Status: "422", Detail: "second"},
+		{ID: "existing", Status: "400", Detail: "third"},
+	}
+
+	stamped := errs.StampID("req-123")
+	assert.Equal(t, "req-123", stamped[0].ID)
+	assert.Equal(t, "req-123", stamped[1].ID)
+	assert.Equal(t, "existing", stamped[2].ID)
+	assert.Empty(t, errs[0].ID, "original errors are not modified")
+
+	w := httptest.NewRecorder()
+	_, err := (&Context{}).MarshalErrors(w, http.StatusUnprocessableEntity, stamped)
+	require.NoError(t, err)
+
+	var doc Document
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	require.Len(t, doc.Errors, 3)
+	for _, e := range doc.Errors[:2] {
+		assert.Equal(t, "req-123", e.ID)
+	}
+}
+
+func TestError_Meta(t *testing.T) {
+	apiErr := &Error{
+		Status: "503",
+		Title:  "Service Unavailable",
+		Meta:   map[string]interface{}{"traceId": "abc", "retryAfter": float64(30)},
+	}
+
+	w := httptest.NewRecorder()
+	_, err := (&Context{}).MarshalErrors(w, http.StatusServiceUnavailable, fmt.Errorf("fetch: %w", apiErr))
+	require.NoError(t, err)
+
+	var raw struct {
+		Errors []map[string]json.RawMessage `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &raw))
+	require.Len(t, raw.Errors, 1)
+	assert.JSONEq(t, `{"traceId": "abc", "retryAfter": 30}`, string(raw.Errors[0]["meta"]))
+
+	var doc Document
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, apiErr.Meta, doc.Errors[0].Meta)
+}
+
+func TestErrorsFromFieldMap(t *testing.T) {
+	t.Run("maps fields to attribute pointers", func(t *testing.T) {
+		errs := ErrorsFromFieldMap(map[string]string{
+			"Name":      "name is required",
+			"CreatedAt": "created at is invalid",
+			"ID":        "id is invalid",
+			"URLPath":   "url path is invalid",
+			"title":     "title is required",
+		})
+
+		require.Len(t, errs, 5)
+		assert.Equal(t, "/data/attributes/createdAt", errs[0].Source.Pointer)
+		assert.Equal(t, "/data/attributes/id", errs[1].Source.Pointer)
+		assert.Equal(t, "/data/attributes/name", errs[2].Source.Pointer)
+		assert.Equal(t, "name is required", errs[2].Detail)
+		assert.Equal(t, "/data/attributes/title", errs[3].Source.Pointer)
+		assert.Equal(t, "/data/attributes/urlPath", errs[4].Source.Pointer)
+		for _, err := range errs {
+			assert.Equal(t, "422", err.Status)
+			assert.Equal(t, "Unprocessable Entity", err.Title)
+		}
+	})
+
+	t.Run("empty map", func(t *testing.T) {
+		assert.Empty(t, ErrorsFromFieldMap(nil))
+	})
+}
+
+func TestErrorsFromStructFields(t *testing.T) {
+	type profile struct {
+		ID       string `json:"-"`
+		Name     string `json:"name"`
+		Nickname string `json:"nick_name,omitempty"`
+		Age      int
+	}
+
+	t.Run("maps fields to attribute pointers", func(t *testing.T) {
+		errs := ErrorsFromStructFields(profile{}, map[string]string{
+			"Name":     "name is required",
+			"Nickname": "nickname is too long",
+			"Age":      "age must be positive",
+			"Unknown":  "unknown field",
+		})
+
+		require.Len(t, errs, 4)
+		assert.Equal(t, "/data/attributes/Age", errs[0].Source.Pointer)
+		assert.Equal(t, "/data/attributes/Unknown", errs[1].Source.Pointer)
+		assert.Equal(t, "/data/attributes/name", errs[2].Source.Pointer)
+		assert.Equal(t, "name is required", errs[2].Detail)
+		assert.Equal(t, "/data/attributes/nick_name", errs[3].Source.Pointer)
+		for _, err := range errs {
+			assert.Equal(t, "422", err.Status)
+			assert.Equal(t, "Unprocessable Entity", err.Title)
+		}
+	})
+
+	t.Run("pointer to struct", func(t *testing.T) {
+		errs := ErrorsFromStructFields(&profile{}, map[string]string{"Name": "required"})
+		require.Len(t, errs, 1)
+		assert.Equal(t, "/data/attributes/name", errs[0].Source.Pointer)
+	})
+
+	t.Run("fields that are not attributes have no pointer", func(t *testing.T) {
+		errs := ErrorsFromStructFields(profile{}, map[string]string{"ID": "invalid id"})
+		require.Len(t, errs, 1)
+		assert.Empty(t, errs[0].Source.Pointer)
+		assert.Equal(t, "invalid id", errs[0].Detail)
+	})
+
+	t.Run("nil resource uses field names", func(t *testing.T) {
+		errs := ErrorsFromStructFields(nil, map[string]string{"title": "required"})
+		require.Len(t, errs, 1)
+		assert.Equal(t, "/data/attributes/title", errs[0].Source.Pointer)
+	})
+
+	t.Run("writes one error object per field", func(t *testing.T) {
+		errs := ErrorsFromStructFields(profile{}, map[string]string{
+			"Name": "name is required",
+			"Age":  "age must be positive",
+		})
+
+		w := httptest.NewRecorder()
+		_, err := (&Context{}).MarshalErrors(w, http.StatusUnprocessableEntity, errs)
+		require.NoError(t, err)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		require.Len(t, doc.Errors, 2)
+		assert.Equal(t, "/data/attributes/Age", doc.Errors[0].Source.Pointer)
+		assert.Equal(t, "/data/attributes/name", doc.Errors[1].Source.Pointer)
+	})
+}
+
+func TestPointerForField(t *testing.T) {
+	type audit struct {
+		CreatedBy string `json:"created_by"`
+	}
+	type profile struct {
+		audit
+		Name     string `json:"name"`
+		Nickname string `json:"nick_name,omitempty"`
+		Age      int
+		Secret   string `json:"-"`
+	}
+
+	tests := []struct {
+		name  string
+		v     interface{}
+		field string
+		want  string
+	}{
+		{"tagged field", profile{}, "Name", "/data/attributes/name"},
+		{"tag with options", profile{}, "Nickname", "/data/attributes/nick_name"},
+		{"untagged field", profile{}, "Age", "/data/attributes/Age"},
+		{"promoted field", profile{}, "CreatedBy", "/data/attributes/created_by"},
+		{"unknown field", profile{}, "Missing", "/data/attributes/Missing"},
+		{"pointer to struct", &profile{}, "Name", "/data/attributes/name"},
+		{"excluded field", profile{}, "Secret", ""},
+		{"unexported embedded struct", profile{}, "audit", ""},
+		{"nil resource", nil, "title", "/data/attributes/title"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, PointerForField(tt.v, tt.field))
+		})
+	}
+}
+
+func TestErrorSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		source ErrorSource
+		want   string
+	}{
+		{"pointer", ErrorSourcePointer("/data/attributes/title"), `{"pointer":"/data/attributes/title"}`},
+		{"parameter", ErrorSourceParameter("include"), `{"parameter":"include"}`},
+		{"header", ErrorSourceHeader("Content-Type"), `{"header":"Content-Type"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.source)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(data))
+		})
+	}
+}
+
+func TestError_WithSource(t *testing.T) {
+	e := &Error{Status: "400", Detail: "missing header"}
+	got := e.WithSource(ErrorSourceHeader("If-Match"))
+	assert.Same(t, e, got)
+	assert.Equal(t, "If-Match", e.Source.Header)
+
+	data, err := json.Marshal(Document{Errors: []*Error{got}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"errors":[{"status":"400","detail":"missing header","source":{"header":"If-Match"}}]}`, string(data))
+
+	var doc Document
+	require.NoError(t, json.Unmarshal(data, &doc))
+	require.Len(t, doc.Errors, 1)
+	assert.Equal(t, ErrorSourceHeader("If-Match"), doc.Errors[0].Source)
+}
+
+func TestErrorFromProblemJSON(t *testing.T) {
+	t.Run("converts problem details", func(t *testing.T) {
+		problem := `{
+			"type": "https://example.com/probs/out-of-credit",
+			"title": "You do not have enough credit.",
+			"status": 403,
+			"detail": "Your current balance is 30, but that costs 50.",
+			"instance": "/account/12345/msgs/abc",
+			"balance": 30
+		}`
+
+		result, err := ErrorFromProblemJSON([]byte(problem))
+		require.NoError(t, err)
+		assert.Equal(t, Error{
+			Status: "403",
+			Title:  "You do not have enough credit.",
+			Detail: "Your current balance is 30, but that costs 50.",
+			Links: map[string]Link{
+				"type":  {Href: "https://example.com/probs/out-of-credit"},
+				"about": {Href: "/account/12345/msgs/abc"},
+			},
+			Meta: map[string]interface{}{"balance": float64(30)},
+		}, result)
+	})
+
+	t.Run("ignores blank type", func(t *testing.T) {
+		result, err := ErrorFromProblemJSON([]byte(`{"type":"about:blank","title":"Not Found","status":404}`))
+		require.NoError(t, err)
+		assert.Equal(t, Error{Status: "404", Title: "Not Found"}, result)
+	})
+
+	t.Run("invalid document", func(t *testing.T) {
+		_, err := ErrorFromProblemJSON([]byte(`not json`))
+		assert.ErrorContains(t, err, "unmarshal problem details")
+	})
+}
+
+func TestStatusForError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "read-only", err: ErrReadOnly, want: http.StatusForbidden},
+		{name: "type mismatch", err: ErrTypeMismatch, want: http.StatusConflict},
+		{name: "empty body", err: ErrEmptyBody, want: http.StatusBadRequest},
+		{name: "wrapped sentinel", err: fmt.Errorf("create article: %w", ErrTypeMismatch), want: http.StatusConflict},
+		{name: "jsonapi error", err: &Error{Status: "422"}, want: http.StatusUnprocessableEntity},
+		{name: "read-only relationship", err: readOnlyError("author", "/data/relationships/author", ErrReadOnly), want: http.StatusForbidden},
+		{name: "unknown error", err: errors.New("boom"), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, StatusForError(tt.err))
+		})
+	}
+}
+
+func TestStatusForError_Unmarshal(t *testing.T) {
+	t.Run("type mismatch", func(t *testing.T) {
+		var article Article
+		err := Unmarshal([]byte(`{"data": {"type": "users", "id": "1"}}`), &article, WithTypeValidation())
+		assert.ErrorIs(t, err, ErrTypeMismatch)
+		assert.Equal(t, http.StatusConflict, StatusForError(err))
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		var article Article
+		err := Unmarshal([]byte(`{"meta": {}}`), &article)
+		assert.ErrorIs(t, err, ErrEmptyBody)
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, http.StatusBadRequest, StatusForError(err))
+	})
+
+	t.Run("written by Context.Fail", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, err := (&Context{}).Fail(w, fmt.Errorf("decode: %w", ErrEmptyBody))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"400"`)
+	})
+}
@@ -2,9 +2,13 @@ package jsonapi
 
 import (
 	"context"
+	"errors"
 	"io"
+	"mime"
 	"net/http"
+	"reflect"
 	"strconv"
+	"strings"
 )
 
 // Context represents a parsed JSON:API HTTP request containing information
@@ -14,6 +18,27 @@ type Context struct {
 	ResourceType string // Type of the target resource
 	Relationship string // Name of the relationship being accessed
 	Related      bool   // Whether this is a request for related resources
+	Action       string // Name of the custom action being invoked, e.g. "publish"
+
+	// Resource holds the target resource loaded by [ResourceHandler.Loader],
+	// if one is configured. It is nil otherwise.
+	Resource interface{}
+
+	// Extra holds additional request information, such as tenant or user identifiers,
+	// populated by a [RequestResolver] like the one returned by [ResolveHeaders] or by
+	// middleware further up the chain.
+	Extra map[string]string
+
+	// SparseFields holds the sparse fieldsets requested through fields[type] query parameters,
+	// populated by resolvers such as [DefaultRequestResolver] when enabled. When set, they are
+	// applied to the primary and included resources marshaled by [Context.Marshal] and related methods.
+	SparseFields map[string][]string
+
+	// ErrorOptions holds document options applied to every error document written through
+	// the context, such as [WithErrorTitleMapper], by [Context.MarshalErrors], [Context.Fail]
+	// and related methods, as well as by [ResourceHandler]. It is typically populated by a
+	// [RequestResolver] or middleware further up the chain.
+	ErrorOptions []Options
 
 	// If true, then this context has been resolved by a [RequestResolver]
 	// in the request chain. Primarily used to override request resolution
@@ -42,7 +67,25 @@ func FromContext(ctx context.Context) *Context {
 	return request
 }
 
+// IsRelationshipLinkage reports whether the request targets a relationship endpoint
+// such as /articles/1/relationships/author, which reads or writes resource linkage.
+func (c *Context) IsRelationshipLinkage() bool {
+	return c.Relationship != "" && !c.Related
+}
+
+// IsRelated reports whether the request targets a related resource endpoint
+// such as /articles/1/author, which returns the related resources themselves.
+func (c *Context) IsRelated() bool {
+	return c.Relationship != "" && c.Related
+}
+
 // Unmarshal reads the request body and unmarshals JSON:API data into the target.
+// Pass [WithValidationContext] with the request context so that targets implementing
+// [ResourceValidator] are validated with it.
+//
+// Example:
+//
+//	err := ctx.Unmarshal(r.Body, &article, jsonapi.WithValidationContext(r.Context()))
 func (c *Context) Unmarshal(r io.Reader, target interface{}, opts ...Options) error {
 	body, err := io.ReadAll(r)
 	if err != nil {
@@ -60,11 +103,130 @@ func (c *Context) UnmarshalRef(r io.Reader, name string, target RelationshipUnma
 	return UnmarshalRef(body, name, target, opts...)
 }
 
+// UnmarshalRefReplace reads the request body and replaces the target's relationship data.
+// See [UnmarshalRefReplace].
+func (c *Context) UnmarshalRefReplace(r io.Reader, name string, target RelationshipClearer, opts ...Options) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return UnmarshalRefReplace(body, name, target, opts...)
+}
+
+// UnmarshalIdentifiers reads the request body and returns the resource identifiers
+// of a relationship document. See [UnmarshalIdentifiers].
+func (c *Context) UnmarshalIdentifiers(r io.Reader) ([]ResourceIdentifier, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalIdentifiers(body)
+}
+
+// AcceptsJSONAPI reports whether the client accepts a JSON:API response according to the
+// request's Accept header. A missing header, wildcards such as */* and application/*, and
+// the JSON:API media type with only the ext and profile parameters are acceptable. Media
+// ranges with a quality value of zero are ignored.
+//
+// Example:
+//
+//	if !ctx.AcceptsJSONAPI(r) {
+//		json.NewEncoder(w).Encode(article) // fall back to plain JSON
+//		return
+//	}
+func (c *Context) AcceptsJSONAPI(r *http.Request) bool {
+	values := r.Header.Values("Accept")
+	if len(values) == 0 {
+		return true
+	}
+
+	for _, value := range values {
+		for _, accept := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+			if err != nil {
+				continue
+			}
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+				continue
+			}
+			switch mediaType {
+			case "*/*", "application/*":
+				return true
+			case jsonapiContentType:
+				if acceptableMediaParams(params) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// acceptableMediaParams reports whether the JSON:API media type parameters are limited
+// to those permitted by the specification.
+func acceptableMediaParams(params map[string]string) bool {
+	for key := range params {
+		if key != "ext" && key != "profile" && key != "q" {
+			return false
+		}
+	}
+	return true
+}
+
+// errorOptions prepends the context's [Context.ErrorOptions] to opts so that explicit
+// options take precedence.
+func (c *Context) errorOptions(opts []Options) []Options {
+	return append(c.ErrorOptions[:len(c.ErrorOptions):len(c.ErrorOptions)], opts...)
+}
+
+// options prepends the marshaling options derived from the context, such as its sparse
+// fieldsets, to opts so that explicit options take precedence.
+func (c *Context) options(opts []Options) []Options {
+	if c.SparseFields == nil {
+		return opts[:len(opts):len(opts)]
+	}
+	return append([]Options{optionsFunc(func(o *options) {
+		o.sparseFields = c.SparseFields
+	})}, opts...)
+}
+
 // Marshal marshals data into a JSON:API document and writes it to the HTTP response.
 // It sets the appropriate Content-Type header and HTTP status code, returning the number
 // of bytes written and any marshaling or writing errors.
 func (c *Context) Marshal(w http.ResponseWriter, status int, data interface{}, opts ...Options) (n int, err error) {
-	return write(w, status, data, opts...)
+	return write(w, status, data, c.options(opts)...)
+}
+
+// MarshalList marshals a collection into a JSON:API document with the total number of
+// resources in the collection, across all pages, set as the top-level meta.total member.
+// See [Context.Marshal].
+//
+// Example:
+//
+//	page, total := store.ListArticles(offset, limit)
+//	ctx.MarshalList(w, http.StatusOK, page, total)
+func (c *Context) MarshalList(w http.ResponseWriter, status int, data interface{}, total int, opts ...Options) (n int, err error) {
+	return write(w, status, data, append(c.options(opts), WithTopMeta("total", total))...)
+}
+
+// Accepted writes a 202 Accepted response for a request that will be processed asynchronously.
+// The Content-Location header is set to contentLocation when it is non-empty, typically pointing
+// to a status resource the client can poll. If data is non-nil, it is marshaled as the response
+// document; otherwise the response has no body.
+//
+// Example:
+//
+//	job := jobs.Enqueue(article)
+//	ctx.Accepted(w, "/jobs/"+job.ID, job)
+func (c *Context) Accepted(w http.ResponseWriter, contentLocation string, data interface{}, opts ...Options) (n int, err error) {
+	if contentLocation != "" {
+		w.Header().Set("Content-Location", contentLocation)
+	}
+	if data == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return 0, nil
+	}
+	return write(w, http.StatusAccepted, data, c.options(opts)...)
 }
 
 // MarshalRef marshals a specific relationship from a resource into a JSON:API document
@@ -93,13 +255,60 @@ func (c *Context) Marshal(w http.ResponseWriter, status int, data interface{}, o
 // The relationship must be defined in the resource's [RelationshipMarshaler.Relationships] method,
 // otherwise an error is returned during marshaling.
 func (c *Context) MarshalRef(w http.ResponseWriter, status int, name string, data RelationshipMarshaler, opts ...Options) (n int, err error) {
-	return writeRef(w, status, name, data, opts...)
+	return writeRef(w, status, name, data, c.options(opts)...)
 }
 
 // MarshalErrors creates a JSON:API error document from the provided errors and writes it to the response.
 // Each error is converted to a JSON:API error object with the specified HTTP status code.
 func (c *Context) MarshalErrors(w http.ResponseWriter, status int, errs ...error) (n int, err error) {
-	return writeErrors(w, status, errs...)
+	return writeErrorsWith(w, status, errs, c.errorOptions(nil)...)
+}
+
+// MarshalErrorsWith is like [Context.MarshalErrors], but applies additional document
+// options, such as top-level links pointing to support or documentation pages.
+//
+// Example:
+//
+//	ctx.MarshalErrorsWith(w, http.StatusForbidden, []error{err},
+//		jsonapi.WithTopHref("help", "https://example.com/docs/permissions"))
+func (c *Context) MarshalErrorsWith(w http.ResponseWriter, status int, errs []error, opts ...Options) (n int, err error) {
+	return writeErrorsWith(w, status, errs, c.errorOptions(opts)...)
+}
+
+// Fail writes a JSON:API error document for err, using the HTTP status code
+// chosen by [StatusForError].
+//
+// Example:
+//
+//	if err := ctx.Unmarshal(r.Body, &article); err != nil {
+//		ctx.Fail(w, err)
+//		return
+//	}
+func (c *Context) Fail(w http.ResponseWriter, err error) (n int, werr error) {
+	return writeErrorsWith(w, StatusForError(err), []error{err}, c.errorOptions(nil)...)
+}
+
+// FieldError describes an invalid attribute of a resource, for use with [Context.FailValidation].
+type FieldError struct {
+	Attr   string // Name of the invalid attribute
+	Detail string // Human-readable explanation of the problem
+}
+
+// FailValidation writes a 422 Unprocessable Entity error document with one error per
+// [FieldError], each with a source pointer to the offending attribute.
+//
+// Example:
+//
+//	if article.Title == "" {
+//		ctx.FailValidation(w, jsonapi.FieldError{Attr: "title", Detail: "title is required"})
+//		return
+//	}
+func (c *Context) FailValidation(w http.ResponseWriter, errs ...FieldError) (n int, err error) {
+	converted := make([]error, len(errs))
+	for i, fe := range errs {
+		converted[i] = attributeError("/data/attributes/"+fe.Attr, fe.Detail)
+	}
+	return writeErrorsWith(w, http.StatusUnprocessableEntity, converted, c.errorOptions(nil)...)
 }
 
 // RequestResolver defines the interface for parsing HTTP requests into JSON:API request objects.
@@ -109,6 +318,40 @@ type RequestResolver interface {
 	ResolveJSONAPIRequest(r *http.Request) *Context
 }
 
+// RequestResolverFunc is a function type that implements the [RequestResolver] interface.
+type RequestResolverFunc func(r *http.Request) *Context
+
+// ResolveJSONAPIRequest implements the [RequestResolver] interface for [RequestResolverFunc].
+func (f RequestResolverFunc) ResolveJSONAPIRequest(r *http.Request) *Context {
+	return f(r)
+}
+
+// ResolveHeaders wraps a [RequestResolver] so that the values of the named request headers
+// are copied into [Context.Extra], keyed by the header name as provided. Headers missing
+// from the request are skipped.
+//
+// Example:
+//
+//	resolver := jsonapi.ResolveHeaders(jsonapi.DefaultRequestResolver{}, "X-Tenant-ID")
+//	handler := jsonapi.Handle(resolver, mux)
+//	// in a handler: jsonapi.FromContext(r.Context()).Extra["X-Tenant-ID"]
+func ResolveHeaders(resolver RequestResolver, headers ...string) RequestResolver {
+	return RequestResolverFunc(func(r *http.Request) *Context {
+		request := resolver.ResolveJSONAPIRequest(r)
+		for _, header := range headers {
+			value := r.Header.Get(header)
+			if value == "" {
+				continue
+			}
+			if request.Extra == nil {
+				request.Extra = make(map[string]string)
+			}
+			request.Extra[header] = value
+		}
+		return request
+	})
+}
+
 // Handle creates HTTP middleware that wraps a handler with JSON:API request parsing functionality.
 // It uses the provided resolver to extract JSON:API request information from HTTP requests
 // and adds this information to the request context for use by downstream handlers.
@@ -133,15 +376,20 @@ func Handle(resolver RequestResolver, handler http.Handler) http.Handler {
 // from URL path parameters using Go 1.22+ ServeMux path value functionality. Typically used
 // in conjunction with [DefaultServeMux], this resolver is opinionated on the path variable names
 // in incoming request URL paths.
-type DefaultRequestResolver struct{}
+type DefaultRequestResolver struct {
+	// SparseFieldsets enables recording the fields[type] query parameters in [Context.SparseFields],
+	// so that responses written through the [Context] apply them automatically.
+	SparseFieldsets bool
+}
 
 // ResolveJSONAPIRequest extracts resource information from URL path parameters.
-// It expects path parameters named "id", "type", "ref", and "related" to be present in the request.
+// It expects path parameters named "id", "type", "ref", "related", and "action" to be present in the request.
 func (p DefaultRequestResolver) ResolveJSONAPIRequest(r *http.Request) *Context {
 	request := &Context{
 		ResourceID:   r.PathValue("id"),
 		ResourceType: r.PathValue("type"),
 		Relationship: r.PathValue("ref"),
+		Action:       r.PathValue("action"),
 	}
 
 	related := r.PathValue("related")
@@ -149,6 +397,9 @@ func (p DefaultRequestResolver) ResolveJSONAPIRequest(r *http.Request) *Context
 		request.Related = true
 		request.Relationship = related
 	}
+	if p.SparseFieldsets {
+		request.SparseFields = parseFields(r.URL.Query())
+	}
 	return request
 }
 
@@ -171,6 +422,7 @@ func DefaultServeMux(handlers map[string]ResourceHandler, middleware ...Middlewa
 		"PATCH  /{type}/{id}/relationships/{ref}", // update relationship
 		"DELETE /{type}/{id}/relationships/{ref}", // remove from many relationship
 		"GET    /{type}/{id}/{related}",           // get related resource
+		"POST   /{type}/{id}/{action}",            // invoke custom resource action
 		"GET    /{type}/{id}",                     // get resource
 		"PATCH  /{type}/{id}",                     // update resource
 		"DELETE /{type}/{id}",                     // delete resource
@@ -196,7 +448,7 @@ func (m ResourceHandlerMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	request := FromContext(r.Context())
 
 	if request.ResourceType == "" {
-		write404(w)
+		write404(w, request, "")
 		return
 	}
 
@@ -205,7 +457,7 @@ func (m ResourceHandlerMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	write404(w)
+	write404(w, request, "")
 }
 
 // ResourceHandler contains HTTP handlers for all standard JSON:API resource operations.
@@ -217,6 +469,25 @@ type ResourceHandler struct {
 	Delete   http.Handler // Handles DELETE /{type}/{id} - delete resource
 	List     http.Handler // Handles GET /{type} - list resource collection
 	Refs     http.Handler // Handles relationship and related resource operations
+
+	// Actions maps custom action names to their handlers, for non-CRUD endpoints
+	// such as POST /{type}/{id}/publish. Requests for unknown actions result in
+	// a 404 Not Found response.
+	Actions map[string]http.Handler
+
+	// Loader, if set, loads the target resource for requests that address a single
+	// resource by ID, before the operation handler is invoked. The loaded resource is
+	// available to handlers through [Context.Resource]. A nil resource results in a
+	// 404 Not Found response. An error results in a response with the status chosen by
+	// [StatusForError]; errors other than an [Error] are reported with a generic detail so
+	// that internal failures are not exposed to clients.
+	Loader func(ctx *Context) (interface{}, error)
+
+	// NotFoundDetail, if set, replaces the detail of the 404 Not Found errors written by the
+	// handler, such as when the Loader finds no resource or no handler serves the operation.
+	// The placeholders {type} and {id} are replaced with the requested resource type and ID,
+	// e.g. "Article {id} not found".
+	NotFoundDetail string
 }
 
 // ServeHTTP routes HTTP requests to the appropriate handler based on the HTTP method
@@ -226,64 +497,145 @@ func (h ResourceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	request := FromContext(r.Context())
 
 	if request.ResourceType == "" {
-		write404(w)
+		write404(w, request, h.NotFoundDetail)
 		return
 	}
 
+	if h.Loader != nil && request.ResourceID != "" {
+		resource, err := h.Loader(request)
+		if err != nil {
+			writeLoadError(w, err, request.ErrorOptions...)
+			return
+		}
+		if isNil(resource) {
+			write404(w, request, h.NotFoundDetail)
+			return
+		}
+		request.Resource = resource
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		if request.Related && request.ResourceID != "" {
 			// serve get related resources
-			tryServeHTTP(w, r, h.Refs)
+			tryServeHTTP(w, r, request, h.NotFoundDetail, h.Refs)
 			return
 		}
 		if request.ResourceID != "" {
 			// serve get resource
-			tryServeHTTP(w, r, h.Retrieve)
+			tryServeHTTP(w, r, request, h.NotFoundDetail, h.Retrieve)
 			return
 		} else {
 			// serve list resource collection
-			tryServeHTTP(w, r, h.List)
+			tryServeHTTP(w, r, request, h.NotFoundDetail, h.List)
 			return
 		}
 	case http.MethodPost:
 		if request.ResourceID != "" && request.Relationship != "" {
 			// forward relationship add to refs handler
-			tryServeHTTP(w, r, h.Refs)
+			tryServeHTTP(w, r, request, h.NotFoundDetail, h.Refs)
+			return
+		}
+		if request.ResourceID != "" && request.Action != "" {
+			// serve custom resource action
+			tryServeHTTP(w, r, request, h.NotFoundDetail, h.Actions[request.Action])
 			return
 		}
 		// server create resource
 		if request.ResourceID == "" {
-			tryServeHTTP(w, r, h.Create)
+			tryServeHTTP(w, r, request, h.NotFoundDetail, h.Create)
 			return
 		}
 	case http.MethodPatch:
 		if request.ResourceID != "" && request.Relationship != "" {
 			// forward relationship update to refs handler
-			tryServeHTTP(w, r, h.Refs)
+			tryServeHTTP(w, r, request, h.NotFoundDetail, h.Refs)
 			return
 		}
 		if request.ResourceID != "" {
 			// serve update relationship
-			tryServeHTTP(w, r, h.Update)
+			tryServeHTTP(w, r, request, h.NotFoundDetail, h.Update)
 			return
 		}
 	case http.MethodDelete:
 		if request.ResourceID != "" && request.Relationship != "" {
 			// forward relationship remove to refs handler
-			tryServeHTTP(w, r, h.Refs)
+			tryServeHTTP(w, r, request, h.NotFoundDetail, h.Refs)
 			return
 		}
 		if request.ResourceID != "" {
 			// serve resource delete
-			tryServeHTTP(w, r, h.Delete)
+			tryServeHTTP(w, r, request, h.NotFoundDetail, h.Delete)
 		}
 	default:
-		write404(w)
+		write404(w, request, h.NotFoundDetail)
 		return
 	}
 }
 
+// Operation identifies a standard resource operation served by a [ResourceHandler].
+type Operation string
+
+// Standard resource operations, matching the fields of [ResourceHandler].
+const (
+	OperationCreate   Operation = "create"   // POST /{type}
+	OperationRetrieve Operation = "retrieve" // GET /{type}/{id}
+	OperationUpdate   Operation = "update"   // PATCH /{type}/{id}
+	OperationDelete   Operation = "delete"   // DELETE /{type}/{id}
+	OperationList     Operation = "list"     // GET /{type}
+	OperationRefs     Operation = "refs"     // relationship and related resource operations
+)
+
+// WithOperationMiddleware returns a copy of the handler whose handler for the given
+// operation is wrapped with the provided [Middleware], so that it runs only for that
+// operation. Middleware is applied as with [Use]. Operations without a handler are
+// left unchanged and still result in a 404 Not Found response.
+//
+// Example:
+//
+//	handler := jsonapi.ResourceHandler{Create: create, List: list}.
+//		WithOperationMiddleware(jsonapi.OperationCreate, requireAuth)
+func (h ResourceHandler) WithOperationMiddleware(op Operation, middleware ...Middleware) ResourceHandler {
+	var target *http.Handler
+	switch op {
+	case OperationCreate:
+		target = &h.Create
+	case OperationRetrieve:
+		target = &h.Retrieve
+	case OperationUpdate:
+		target = &h.Update
+	case OperationDelete:
+		target = &h.Delete
+	case OperationList:
+		target = &h.List
+	case OperationRefs:
+		target = &h.Refs
+	default:
+		return h
+	}
+
+	if *target != nil {
+		*target = Use(*target, middleware...)
+	}
+	return h
+}
+
+// writeLoadError writes the error returned by a [ResourceHandler] Loader. JSON:API errors
+// are written as they are; other errors are replaced with a generic error of the status
+// chosen by [StatusForError].
+func writeLoadError(w http.ResponseWriter, err error, opts ...Options) {
+	status := StatusForError(err)
+	var jsonErr *Error
+	if !errors.As(err, &jsonErr) {
+		err = &Error{
+			Status: strconv.Itoa(status),
+			Title:  http.StatusText(status),
+			Detail: "The resource could not be loaded",
+		}
+	}
+	writeErrorsWith(w, status, []error{err}, opts...)
+}
+
 // RelationshipHandlerMux maps relationship names to their corresponding handlers.
 // It implements [http.Handler] and routes requests based on the relationship name
 // extracted from the request context.
@@ -294,7 +646,7 @@ func (m RelationshipHandlerMux) ServeHTTP(w http.ResponseWriter, r *http.Request
 	request := FromContext(r.Context())
 
 	if request.Relationship == "" || request.ResourceID == "" {
-		write404(w)
+		write404(w, request, "")
 		return
 	}
 
@@ -303,7 +655,7 @@ func (m RelationshipHandlerMux) ServeHTTP(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	write404(w)
+	write404(w, request, "")
 }
 
 // RelationshipHandler contains HTTP handlers for JSON:API relationship operations.
@@ -313,6 +665,10 @@ type RelationshipHandler struct {
 	Del    http.Handler // Handles DELETE - remove from many-to-many relationship
 	Get    http.Handler // Handles GET - retrieve relationship data
 	Update http.Handler // Handles PATCH - replace relationship data
+
+	// NotFoundDetail, if set, replaces the detail of the 404 Not Found errors written by the
+	// handler. See [ResourceHandler.NotFoundDetail].
+	NotFoundDetail string
 }
 
 // ServeHTTP routes HTTP requests to the appropriate relationship handler based on the HTTP method.
@@ -321,29 +677,29 @@ func (h RelationshipHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	request := FromContext(r.Context())
 
 	if request.Relationship == "" || request.ResourceID == "" {
-		write404(w)
+		write404(w, request, h.NotFoundDetail)
 		return
 	}
 
 	switch r.Method {
 	case http.MethodGet:
 		// serve get related resources
-		tryServeHTTP(w, r, h.Get)
+		tryServeHTTP(w, r, request, h.NotFoundDetail, h.Get)
 		return
 	case http.MethodPost:
 		// serve add to many ref
-		tryServeHTTP(w, r, h.Add)
+		tryServeHTTP(w, r, request, h.NotFoundDetail, h.Add)
 		return
 	case http.MethodPatch:
 		// serve update ref
-		tryServeHTTP(w, r, h.Update)
+		tryServeHTTP(w, r, request, h.NotFoundDetail, h.Update)
 		return
 	case http.MethodDelete:
 		// serve remove from many ref
-		tryServeHTTP(w, r, h.Del)
+		tryServeHTTP(w, r, request, h.NotFoundDetail, h.Del)
 		return
 	default:
-		write404(w)
+		write404(w, request, h.NotFoundDetail)
 		return
 	}
 }
@@ -353,6 +709,16 @@ func (h RelationshipHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // of bytes written and any marshaling or writing errors.
 func write(w http.ResponseWriter, status int, data any, opts ...Options) (n int, werr error) {
 	options := applyOptions(opts)
+	if options.stream {
+		doc, err := marshalValue(data, &options)
+		if err != nil {
+			return 0, err
+		}
+		w.Header().Add("Content-Type", "application/vnd.api+json")
+		w.WriteHeader(status)
+		return encodeTo(w, doc, &options)
+	}
+
 	out, err := Marshal(data, fromOptionsOverride(&options))
 	if err != nil {
 		return 0, err
@@ -381,27 +747,49 @@ func writeRef(w http.ResponseWriter, status int, name string, data RelationshipM
 // WriteErrors creates a JSON:API error document from the provided errors and writes it to the response.
 // Each error is converted to a JSON:API error object with the specified HTTP status code.
 func writeErrors(w http.ResponseWriter, status int, errs ...error) (n int, werr error) {
-	opts := make([]Options, len(errs))
-	for i, err := range errs {
-		opts[i] = WithError(status, err)
+	return writeErrorsWith(w, status, errs)
+}
+
+// writeErrorsWith writes a JSON:API error document from the provided errors,
+// applying any additional document options such as top-level links.
+func writeErrorsWith(w http.ResponseWriter, status int, errs []error, opts ...Options) (n int, werr error) {
+	errOpts := make([]Options, 0, len(errs)+len(opts))
+	for _, err := range errs {
+		errOpts = append(errOpts, WithError(status, err))
 	}
-	return write(w, status, nil, opts...)
+	return write(w, status, nil, append(errOpts, opts...)...)
 }
 
-// write404 writes a standard JSON:API 404 Not Found error response.
-func write404(w http.ResponseWriter) (n int, err error) {
-	return writeErrors(w, http.StatusNotFound, &Error{
+// write404 writes a JSON:API 404 Not Found error response with the context's error options.
+// The detail defaults to "Resource not found"; otherwise the placeholders {type} and {id} of
+// the detail template are replaced with the requested resource type and ID.
+func write404(w http.ResponseWriter, ctx *Context, detail string) (n int, err error) {
+	if detail == "" {
+		detail = "Resource not found"
+	} else {
+		detail = strings.NewReplacer("{type}", ctx.ResourceType, "{id}", ctx.ResourceID).Replace(detail)
+	}
+	return writeErrorsWith(w, http.StatusNotFound, []error{&Error{
 		Status: strconv.Itoa(http.StatusNotFound),
 		Title:  http.StatusText(http.StatusNotFound),
-		Detail: "Resource not found",
-	})
+		Detail: detail,
+	}}, ctx.ErrorOptions...)
+}
+
+// isNil reports whether v is nil or a nil pointer.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	value := reflect.ValueOf(v)
+	return value.Kind() == reflect.Ptr && value.IsNil()
 }
 
 // tryServeHTTP attempts to serve an HTTP request with the provided handler.
-// If the handler is nil, it writes a 404 Not Found response instead.
-func tryServeHTTP(w http.ResponseWriter, r *http.Request, h http.Handler) {
+// If the handler is nil, it writes a 404 Not Found response with the detail instead.
+func tryServeHTTP(w http.ResponseWriter, r *http.Request, ctx *Context, detail string, h http.Handler) {
 	if h == nil {
-		write404(w)
+		write404(w, ctx, detail)
 		return
 	}
 	h.ServeHTTP(w, r)
@@ -2,10 +2,14 @@ package jsonapi
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -526,6 +530,71 @@ func TestContext_Marshal(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestContext_MarshalList(t *testing.T) {
+	ctx := &Context{}
+	w := httptest.NewRecorder()
+
+	page := []Article{{ID: "1", Title: "First"}, {ID: "2", Title: "Second"}}
+
+	_, err := ctx.MarshalList(w, http.StatusOK, page, 42, WithMaxIncludeDepth(0))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var doc Document
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Len(t, doc.Data.many, 2)
+	assert.Equal(t, map[string]interface{}{"total": float64(42)}, doc.Meta)
+	assert.Empty(t, doc.Included)
+}
+
+func TestContext_Marshal_Streaming(t *testing.T) {
+	ctx := &Context{}
+	articles := []Article{{ID: "1", Title: "First"}, {ID: "2", Title: "Second"}}
+
+	buffered := httptest.NewRecorder()
+	_, err := ctx.Marshal(buffered, http.StatusOK, articles)
+	require.NoError(t, err)
+
+	streamed := httptest.NewRecorder()
+	n, err := ctx.Marshal(streamed, http.StatusOK, articles, WithStreaming())
+	require.NoError(t, err)
+	assert.Equal(t, streamed.Body.Len(), n)
+	assert.Equal(t, http.StatusOK, streamed.Code)
+	assert.Equal(t, "application/vnd.api+json", streamed.Header().Get("Content-Type"))
+	assert.JSONEq(t, buffered.Body.String(), streamed.Body.String())
+}
+
+func TestContext_Accepted(t *testing.T) {
+	ctx := &Context{}
+
+	t.Run("with data", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		job := &User{ID: "job-1", Name: "pending"}
+
+		n, err := ctx.Accepted(w, "/jobs/job-1", job)
+		require.NoError(t, err)
+		assert.Greater(t, n, 0)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, "/jobs/job-1", w.Header().Get("Content-Location"))
+		assert.Equal(t, "application/vnd.api+json", w.Header().Get("Content-Type"))
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		assert.Equal(t, "job-1", doc.Data.one.ID)
+	})
+
+	t.Run("without data", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		n, err := ctx.Accepted(w, "/jobs/job-2", nil)
+		require.NoError(t, err)
+		assert.Equal(t, 0, n)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, "/jobs/job-2", w.Header().Get("Content-Location"))
+		assert.Empty(t, w.Body.String())
+	})
+}
+
 func TestContext_MarshalErrors(t *testing.T) {
 	ctx := &Context{}
 	w := httptest.NewRecorder()
@@ -540,6 +609,123 @@ func TestContext_MarshalErrors(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestContext_MarshalErrorsWith(t *testing.T) {
+	ctx := &Context{}
+	w := httptest.NewRecorder()
+
+	errs := []error{&Error{Status: "403", Title: "Forbidden"}}
+	_, err := ctx.MarshalErrorsWith(w, http.StatusForbidden, errs,
+		WithTopHref("help", "https://example.com/docs/permissions"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var doc Document
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	require.Len(t, doc.Errors, 1)
+	assert.Equal(t, "Forbidden", doc.Errors[0].Title)
+	assert.Equal(t, "https://example.com/docs/permissions", doc.Links["help"].Href)
+	assert.NotContains(t, w.Body.String(), `"data"`)
+}
+
+func TestContext_ErrorOptions(t *testing.T) {
+	ctx := &Context{ErrorOptions: []Options{
+		WithErrorTitleMapper(func(status int) string { return "Failure " + strconv.Itoa(status) }),
+	}}
+
+	decode := func(t *testing.T, w *httptest.ResponseRecorder) Document {
+		var doc Document
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		return doc
+	}
+
+	t.Run("MarshalErrors", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, err := ctx.MarshalErrors(w, http.StatusNotFound, errors.New("missing"))
+		require.NoError(t, err)
+		doc := decode(t, w)
+		if assert.Len(t, doc.Errors, 1) {
+			assert.Equal(t, "Failure 404", doc.Errors[0].Title)
+		}
+	})
+
+	t.Run("Fail", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, err := ctx.Fail(w, ErrTypeMismatch)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		doc := decode(t, w)
+		if assert.Len(t, doc.Errors, 1) {
+			assert.Equal(t, "Failure 409", doc.Errors[0].Title)
+		}
+	})
+
+	t.Run("explicit options take precedence", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, err := ctx.MarshalErrorsWith(w, http.StatusBadRequest, []error{errors.New("bad")},
+			WithErrorTitleMapper(func(status int) string { return "Explicit" }))
+		require.NoError(t, err)
+		doc := decode(t, w)
+		if assert.Len(t, doc.Errors, 1) {
+			assert.Equal(t, "Explicit", doc.Errors[0].Title)
+		}
+		assert.Len(t, ctx.ErrorOptions, 1)
+	})
+}
+
+func TestContext_SortErrorsBy(t *testing.T) {
+	byStatus := SortErrorsBy(func(a, b Error) bool { return a.Status > b.Status })
+	errs := []error{
+		&Error{Status: "400", Detail: "bad"},
+		&Error{Status: "500", Detail: "server"},
+		&Error{Status: "404", Detail: "missing"},
+	}
+
+	statuses := func(t *testing.T, w *httptest.ResponseRecorder) []string {
+		var doc Document
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		got := make([]string, len(doc.Errors))
+		for i, e := range doc.Errors {
+			got[i] = e.Status
+		}
+		return got
+	}
+
+	t.Run("MarshalErrorsWith", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, err := (&Context{}).MarshalErrorsWith(w, http.StatusBadRequest, errs, byStatus)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"500", "404", "400"}, statuses(t, w))
+	})
+
+	t.Run("ErrorOptions", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, err := (&Context{ErrorOptions: []Options{byStatus}}).MarshalErrors(w, http.StatusBadRequest, errs...)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"500", "404", "400"}, statuses(t, w))
+	})
+}
+
+func TestContext_FailValidation(t *testing.T) {
+	ctx := &Context{}
+	w := httptest.NewRecorder()
+
+	_, err := ctx.FailValidation(w,
+		FieldError{Attr: "title", Detail: "title is required"},
+		FieldError{Attr: "content", Detail: "content is too long"},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var doc Document
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	require.Len(t, doc.Errors, 2)
+	assert.Equal(t, "422", doc.Errors[0].Status)
+	assert.Equal(t, "title is required", doc.Errors[0].Detail)
+	assert.Equal(t, "/data/attributes/title", doc.Errors[0].Source.Pointer)
+	assert.Equal(t, "/data/attributes/content", doc.Errors[1].Source.Pointer)
+}
+
 func TestHandle(t *testing.T) {
 	resolver := DefaultRequestResolver{}
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -602,6 +788,60 @@ func TestDefaultRequestResolver_ResolveJSONAPIRequest(t *testing.T) {
 	})
 }
 
+func TestContext_EndpointShape(t *testing.T) {
+	tests := []struct {
+		name         string
+		pathValues   map[string]string
+		relationship bool
+		related      bool
+	}{
+		{name: "collection", pathValues: map[string]string{"type": "articles"}},
+		{name: "resource", pathValues: map[string]string{"type": "articles", "id": "1"}},
+		{name: "relationship", pathValues: map[string]string{"type": "articles", "id": "1", "ref": "author"}, relationship: true},
+		{name: "related", pathValues: map[string]string{"type": "articles", "id": "1", "related": "author"}, related: true},
+		{name: "action", pathValues: map[string]string{"type": "articles", "id": "1", "action": "publish"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			for key, value := range tt.pathValues {
+				req.SetPathValue(key, value)
+			}
+			ctx := DefaultRequestResolver{}.ResolveJSONAPIRequest(req)
+			assert.Equal(t, tt.relationship, ctx.IsRelationshipLinkage())
+			assert.Equal(t, tt.related, ctx.IsRelated())
+		})
+	}
+}
+
+func TestDefaultRequestResolver_SparseFieldsets(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := FromContext(r.Context())
+		ctx.Marshal(w, http.StatusOK, &User{ID: "1", Name: "Jane"})
+	})
+
+	t.Run("applied when enabled", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/users/1?fields[users]=name", nil)
+		w := httptest.NewRecorder()
+		Handle(DefaultRequestResolver{SparseFieldsets: true}, handler).ServeHTTP(w, req)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		assert.JSONEq(t, `{"name":"Jane"}`, string(doc.Data.one.Attributes))
+	})
+
+	t.Run("ignored by default", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/users/1?fields[users]=name", nil)
+		w := httptest.NewRecorder()
+		Handle(DefaultRequestResolver{}, handler).ServeHTTP(w, req)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		assert.JSONEq(t, `{"id":"1","name":"Jane"}`, string(doc.Data.one.Attributes))
+	})
+}
+
 func TestResourceHandlerMux_ServeHTTP(t *testing.T) {
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -719,12 +959,50 @@ func TestWriteError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestNotFoundDetail(t *testing.T) {
+	serve := func(h http.Handler, method string, ctx *Context) Document {
+		req := httptest.NewRequest(method, "/", nil)
+		req = req.WithContext(WithContext(req.Context(), ctx))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		require.Len(t, doc.Errors, 1)
+		return doc
+	}
+
+	t.Run("unsupported resource operation", func(t *testing.T) {
+		handler := ResourceHandler{NotFoundDetail: "No {type} {id}"}
+		doc := serve(handler, "PATCH", &Context{ResourceType: "articles", ResourceID: "1"})
+		assert.Equal(t, "No articles 1", doc.Errors[0].Detail)
+	})
+
+	t.Run("unsupported relationship operation", func(t *testing.T) {
+		handler := RelationshipHandler{NotFoundDetail: "No relationship on {type} {id}"}
+		doc := serve(handler, "GET", &Context{ResourceType: "articles", ResourceID: "1", Relationship: "tags"})
+		assert.Equal(t, "No relationship on articles 1", doc.Errors[0].Detail)
+	})
+
+	t.Run("unknown resource type applies error options", func(t *testing.T) {
+		mux := ResourceHandlerMux{}
+		doc := serve(mux, "GET", &Context{
+			ResourceType: "videos",
+			ErrorOptions: []Options{WithTopMeta("trace", "abc")},
+		})
+		assert.Equal(t, "Resource not found", doc.Errors[0].Detail)
+		assert.Equal(t, "abc", doc.Meta["trace"])
+	})
+}
+
 func TestTryServeHTTPWithNilHandler(t *testing.T) {
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest("GET", "/test", nil)
 
-	tryServeHTTP(w, req, nil)
+	tryServeHTTP(w, req, &Context{ResourceType: "articles", ResourceID: "1"}, "No {type} {id}", nil)
 	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"detail":"No articles 1"`)
 }
 
 type errorWriter struct{}
@@ -732,3 +1010,273 @@ type errorWriter struct{}
 func (e *errorWriter) Header() http.Header        { return make(http.Header) }
 func (e *errorWriter) WriteHeader(statusCode int) {}
 func (e *errorWriter) Write([]byte) (int, error)  { return 0, assert.AnError }
+
+func TestResourceHandler_Loader(t *testing.T) {
+	loader := func(ctx *Context) (interface{}, error) {
+		if ctx.ResourceID == "error" {
+			return nil, errors.New("db: connection refused on 10.0.0.5")
+		}
+		if ctx.ResourceID == "forbidden" {
+			return nil, &Error{Status: "403", Title: "Forbidden", Detail: "No access to article"}
+		}
+		article, ok := articles[ctx.ResourceID]
+		if !ok {
+			return nil, nil
+		}
+		return &article, nil
+	}
+
+	handler := ResourceHandler{
+		Loader: loader,
+		Retrieve: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := FromContext(r.Context())
+			article := ctx.Resource.(*Article)
+			ctx.Marshal(w, http.StatusOK, article)
+		}),
+		List: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := FromContext(r.Context())
+			assert.Nil(t, ctx.Resource)
+			w.WriteHeader(http.StatusOK)
+		}),
+	}
+
+	serve := func(ctx *Context) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req = req.WithContext(WithContext(req.Context(), ctx))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("loads resource", func(t *testing.T) {
+		w := serve(&Context{ResourceType: "articles", ResourceID: "1"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "First Article")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		w := serve(&Context{ResourceType: "articles", ResourceID: "999"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "Resource not found")
+	})
+
+	t.Run("custom not found detail", func(t *testing.T) {
+		custom := handler
+		custom.NotFoundDetail = "Article {id} not found in {type}"
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req = req.WithContext(WithContext(req.Context(), &Context{ResourceType: "articles", ResourceID: "999"}))
+		w := httptest.NewRecorder()
+		custom.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		var doc Document
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		if assert.Len(t, doc.Errors, 1) {
+			assert.Equal(t, "Article 999 not found in articles", doc.Errors[0].Detail)
+			assert.Equal(t, "404", doc.Errors[0].Status)
+		}
+	})
+
+	t.Run("loader error", func(t *testing.T) {
+		w := serve(&Context{ResourceType: "articles", ResourceID: "error"})
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.NotContains(t, w.Body.String(), "10.0.0.5")
+		assert.Contains(t, w.Body.String(), "The resource could not be loaded")
+	})
+
+	t.Run("loader JSON:API error", func(t *testing.T) {
+		w := serve(&Context{ResourceType: "articles", ResourceID: "forbidden"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "No access to article")
+	})
+
+	t.Run("skipped for collections", func(t *testing.T) {
+		w := serve(&Context{ResourceType: "articles"})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestContext_UnmarshalIdentifiers(t *testing.T) {
+	ctx := &Context{}
+	refs, err := ctx.UnmarshalIdentifiers(strings.NewReader(`{"data": [{"type": "tags", "id": "1"}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []ResourceIdentifier{Ref{Type: "tags", ID: "1"}}, refs)
+
+	_, err = ctx.UnmarshalIdentifiers(iotest.ErrReader(assert.AnError))
+	assert.Error(t, err)
+}
+
+func TestResourceHandler_Actions(t *testing.T) {
+	var published string
+	mux := DefaultServeMux(map[string]ResourceHandler{
+		"articles": {
+			Retrieve: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}),
+			Refs: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			}),
+			Actions: map[string]http.Handler{
+				"publish": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ctx := FromContext(r.Context())
+					published = ctx.ResourceID
+					assert.Equal(t, "publish", ctx.Action)
+					assert.False(t, ctx.Related)
+					w.WriteHeader(http.StatusAccepted)
+				}),
+			},
+		},
+	})
+
+	t.Run("custom action", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/articles/1/publish", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, "1", published)
+	})
+
+	t.Run("unknown action", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/articles/1/archive", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("related resources are not actions", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/articles/1/publish", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusTeapot, w.Code)
+	})
+
+	t.Run("relationship add is not an action", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/articles/1/relationships/tags", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusTeapot, w.Code)
+	})
+}
+
+func TestResolveHeaders(t *testing.T) {
+	var extra map[string]string
+	resolver := ResolveHeaders(DefaultRequestResolver{}, "X-Tenant-ID", "X-User-ID")
+	mux := http.NewServeMux()
+	mux.Handle("GET /{type}/{id}", Handle(resolver, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := FromContext(r.Context())
+		assert.Equal(t, "articles", ctx.ResourceType)
+		assert.Equal(t, "1", ctx.ResourceID)
+		extra = ctx.Extra
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	t.Run("copies present headers", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/articles/1", nil)
+		req.Header.Set("X-Tenant-ID", "acme")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, map[string]string{"X-Tenant-ID": "acme"}, extra)
+	})
+
+	t.Run("no headers present", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/articles/1", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, extra)
+	})
+}
+
+func TestContext_AcceptsJSONAPI(t *testing.T) {
+	tests := []struct {
+		name    string
+		accept  []string
+		accepts bool
+	}{
+		{"missing header", nil, true},
+		{"jsonapi", []string{"application/vnd.api+json"}, true},
+		{"any", []string{"*/*"}, true},
+		{"application wildcard", []string{"application/*"}, true},
+		{"ext and profile parameters", []string{`application/vnd.api+json; ext="https://example.com/ext"; profile="https://example.com/p"`}, true},
+		{"unsupported parameter", []string{"application/vnd.api+json; charset=utf-8"}, false},
+		{"plain json", []string{"application/json"}, false},
+		{"list with jsonapi", []string{"text/html, application/vnd.api+json;q=0.9"}, true},
+		{"multiple header values", []string{"text/html", "application/vnd.api+json"}, true},
+		{"zero quality", []string{"application/vnd.api+json;q=0, application/json"}, false},
+		{"malformed", []string{"not a media type"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/articles", nil)
+			for _, value := range tt.accept {
+				req.Header.Add("Accept", value)
+			}
+			assert.Equal(t, tt.accepts, (&Context{}).AcceptsJSONAPI(req))
+		})
+	}
+}
+
+func TestResourceHandler_WithOperationMiddleware(t *testing.T) {
+	var calls []string
+	auth := MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		calls = append(calls, "auth")
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+	ok := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, name)
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+
+	base := ResourceHandler{Create: ok("create"), List: ok("list")}
+	handler := base.WithOperationMiddleware(OperationCreate, auth).
+		WithOperationMiddleware(OperationDelete, auth)
+
+	serve := func(method string, ctx *Context, authorized bool) int {
+		req := httptest.NewRequest(method, "/", nil)
+		if authorized {
+			req.Header.Set("Authorization", "Bearer token")
+		}
+		req = req.WithContext(WithContext(req.Context(), ctx))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("runs for targeted operation", func(t *testing.T) {
+		calls = nil
+		assert.Equal(t, http.StatusUnauthorized, serve("POST", &Context{ResourceType: "articles"}, false))
+		assert.Equal(t, http.StatusOK, serve("POST", &Context{ResourceType: "articles"}, true))
+		assert.Equal(t, []string{"auth", "auth", "create"}, calls)
+	})
+
+	t.Run("skipped for other operations", func(t *testing.T) {
+		calls = nil
+		assert.Equal(t, http.StatusOK, serve("GET", &Context{ResourceType: "articles"}, false))
+		assert.Equal(t, []string{"list"}, calls)
+	})
+
+	t.Run("missing handler stays not found", func(t *testing.T) {
+		calls = nil
+		assert.Equal(t, http.StatusNotFound, serve("DELETE", &Context{ResourceType: "articles", ResourceID: "1"}, true))
+		assert.Empty(t, calls)
+	})
+
+	t.Run("original handler is unchanged", func(t *testing.T) {
+		calls = nil
+		req := httptest.NewRequest("POST", "/", nil)
+		req = req.WithContext(WithContext(req.Context(), &Context{ResourceType: "articles"}))
+		base.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, []string{"create"}, calls)
+	})
+}
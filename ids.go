@@ -0,0 +1,86 @@
+package jsonapi
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// FormatID converts an identifier of a non-string type into a JSON:API resource ID. Values
+// implementing [encoding.TextMarshaler], such as UUID types, are formatted with MarshalText;
+// otherwise string, integer, and unsigned integer kinds are supported. This is useful when
+// implementing [ResourceIdentifier.ResourceID] for resources with numeric or custom IDs.
+//
+// Example:
+//
+//	func (a Article) ResourceID() string { return jsonapi.FormatID(a.ID) } // ID int64
+func FormatID(v interface{}) string {
+	if marshaler, ok := v.(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(text)
+	}
+
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ParseID stores a JSON:API resource ID in the identifier pointed to by target. It is the
+// inverse of [FormatID]: targets implementing [encoding.TextUnmarshaler] are parsed with
+// UnmarshalText; otherwise string, integer, and unsigned integer kinds are supported.
+// An empty id leaves the target unchanged. This is useful when implementing
+// [ResourceUnmarshaler.SetResourceID].
+//
+// IDs that do not fit in the target's integer type are rejected with an error wrapping
+// [strconv.ErrRange]; they are never truncated. Use a *big.Int target, which implements
+// [encoding.TextUnmarshaler], for numeric IDs of arbitrary size.
+//
+// Example:
+//
+//	func (a *Article) SetResourceID(id string) error { return jsonapi.ParseID(id, &a.ID) }
+func ParseID(id string, target interface{}) error {
+	if id == "" {
+		return nil
+	}
+	if unmarshaler, ok := target.(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(id))
+	}
+
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return fmt.Errorf("%w: id target must be a non-nil pointer, got %T", ErrNotPointer, target)
+	}
+
+	elem := value.Elem()
+	switch elem.Kind() {
+	case reflect.String:
+		elem.SetString(id)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(id, 10, elem.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("parse id %q: %w", id, err)
+		}
+		elem.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(id, 10, elem.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("parse id %q: %w", id, err)
+		}
+		elem.SetUint(n)
+	default:
+		return fmt.Errorf("unsupported id type %s", elem.Type())
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package jsonapi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testUUID is a uuid.UUID-style identifier implementing encoding.TextMarshaler.
+type testUUID [16]byte
+
+func (u testUUID) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])), nil
+}
+
+func (u *testUUID) UnmarshalText(text []byte) error {
+	var raw []byte
+	for _, c := range text {
+		if c != '-' {
+			raw = append(raw, c)
+		}
+	}
+	if len(raw) != 32 {
+		return fmt.Errorf("invalid uuid %q", text)
+	}
+	_, err := hex.Decode(u[:], raw)
+	return err
+}
+
+// uuidArticle is identified by a UUID.
+type uuidArticle struct {
+	ID    testUUID `json:"-"`
+	Title string   `json:"title"`
+}
+
+func (a uuidArticle) ResourceID() string   { return FormatID(a.ID) }
+func (a uuidArticle) ResourceType() string { return "articles" }
+
+func (a *uuidArticle) SetResourceID(id string) error { return ParseID(id, &a.ID) }
+
+// numericArticle is identified by an int64.
+type numericArticle struct {
+	ID    int64  `json:"-"`
+	Title string `json:"title"`
+}
+
+func (a numericArticle) ResourceID() string   { return FormatID(a.ID) }
+func (a numericArticle) ResourceType() string { return "articles" }
+
+func (a *numericArticle) SetResourceID(id string) error { return ParseID(id, &a.ID) }
+
+func TestFormatID(t *testing.T) {
+	assert.Equal(t, "42", FormatID(int64(42)))
+	assert.Equal(t, "-7", FormatID(-7))
+	assert.Equal(t, "9", FormatID(uint8(9)))
+	assert.Equal(t, "abc", FormatID("abc"))
+	assert.Equal(t, "00010203-0405-0607-0809-0a0b0c0d0e0f",
+		FormatID(testUUID{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}))
+}
+
+func TestParseID(t *testing.T) {
+	t.Run("integer", func(t *testing.T) {
+		var id int64
+		require.NoError(t, ParseID("42", &id))
+		assert.Equal(t, int64(42), id)
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		var id int8
+		assert.Error(t, ParseID("300", &id))
+	})
+
+	t.Run("invalid integer", func(t *testing.T) {
+		var id uint
+		assert.ErrorContains(t, ParseID("abc", &id), `parse id "abc"`)
+	})
+
+	t.Run("empty id", func(t *testing.T) {
+		id := int64(5)
+		require.NoError(t, ParseID("", &id))
+		assert.Equal(t, int64(5), id)
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		var id float64
+		assert.ErrorContains(t, ParseID("1.5", &id), "unsupported id type float64")
+	})
+
+	t.Run("not a pointer", func(t *testing.T) {
+		assert.Error(t, ParseID("1", 1))
+	})
+}
+
+func TestIDs_RoundTrip(t *testing.T) {
+	t.Run("int64", func(t *testing.T) {
+		data, err := Marshal(numericArticle{ID: 1234567890123, Title: "Hello"})
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"id":"1234567890123"`)
+
+		var article numericArticle
+		require.NoError(t, Unmarshal(data, &article))
+		assert.Equal(t, int64(1234567890123), article.ID)
+	})
+
+	t.Run("uuid", func(t *testing.T) {
+		original := uuidArticle{ID: testUUID{0xde, 0xad, 0xbe, 0xef, 15: 1}, Title: "Hello"}
+		data, err := Marshal(original)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"id":"deadbeef-0000-0000-0000-000000000001"`)
+
+		var article uuidArticle
+		require.NoError(t, Unmarshal(data, &article))
+		assert.Equal(t, original, article)
+	})
+
+	t.Run("invalid uuid", func(t *testing.T) {
+		var article uuidArticle
+		err := Unmarshal([]byte(`{"data": {"type": "articles", "id": "nope"}}`), &article)
+		assert.ErrorContains(t, err, `invalid uuid "nope"`)
+	})
+}
+
+func TestParseID_Overflow(t *testing.T) {
+	t.Run("exceeds int64", func(t *testing.T) {
+		var id int64
+		err := ParseID("9223372036854775808", &id)
+		assert.ErrorIs(t, err, strconv.ErrRange)
+		assert.Zero(t, id)
+	})
+
+	t.Run("exceeds uint64", func(t *testing.T) {
+		var id uint64
+		assert.ErrorIs(t, ParseID("18446744073709551616", &id), strconv.ErrRange)
+	})
+
+	t.Run("negative into unsigned", func(t *testing.T) {
+		var id uint32
+		assert.Error(t, ParseID("-1", &id))
+	})
+
+	t.Run("boundary values", func(t *testing.T) {
+		var signed int64
+		require.NoError(t, ParseID("9223372036854775807", &signed))
+		assert.Equal(t, int64(math.MaxInt64), signed)
+
+		var unsigned uint64
+		require.NoError(t, ParseID("18446744073709551615", &unsigned))
+		assert.Equal(t, uint64(math.MaxUint64), unsigned)
+	})
+
+	t.Run("big.Int", func(t *testing.T) {
+		id := new(big.Int)
+		require.NoError(t, ParseID("123456789012345678901234567890", id))
+		assert.Equal(t, "123456789012345678901234567890", id.String())
+		assert.Equal(t, "123456789012345678901234567890", FormatID(id))
+	})
+
+	t.Run("unmarshal rejects overflowing resource id", func(t *testing.T) {
+		var article numericArticle
+		err := Unmarshal([]byte(`{"data": {"type": "articles", "id": "99999999999999999999"}}`), &article)
+		assert.ErrorIs(t, err, strconv.ErrRange)
+	})
+}
@@ -0,0 +1,124 @@
+package jsonapi
+
+import (
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// RelationshipNames returns the sorted names of the relationships declared by v through
+// [RelationshipMarshaler]. It returns nil if v does not declare relationships. This is
+// useful for validating include query parameters against known relationships.
+//
+// Example:
+//
+//	names := jsonapi.RelationshipNames(Article{}) // ["author", "tags"]
+func RelationshipNames(v interface{}) []string {
+	marshaler, ok := v.(RelationshipMarshaler)
+	if !ok {
+		return nil
+	}
+
+	names := make([]string, 0, len(marshaler.Relationships()))
+	for name := range marshaler.Relationships() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AttributeNames returns the sorted names of the attributes v marshals, as declared by the
+// json struct tags of its fields and any computed attributes provided through
+// [AttributesMarshaler]. Fields of embedded structs are promoted as with encoding/json.
+// This is useful for validating fields[type] and sort query parameters.
+//
+// Example:
+//
+//	names := jsonapi.AttributeNames(User{}) // ["id", "name"]
+func AttributeNames(v interface{}) []string {
+	if v == nil {
+		return nil
+	}
+
+	var names []string
+	for _, field := range attributeFields(reflect.TypeOf(v)) {
+		names = append(names, field.name)
+	}
+	if marshaler, ok := v.(AttributesMarshaler); ok {
+		for name := range marshaler.MarshalAttributes() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return slices.Compact(names)
+}
+
+// attributeField describes a struct field that marshals as an attribute.
+type attributeField struct {
+	name      string       // Attribute name from the json struct tag, or the Go field name
+	goName    string       // Go field name
+	index     []int        // Field index path, including embedded structs
+	typ       reflect.Type // Field type
+	omitEmpty bool         // Whether the field is tagged omitempty
+}
+
+// attributeFields returns the fields of the struct type t that marshal as attributes, in
+// declaration order. Fields tagged json:"-" and unexported fields are skipped, and fields
+// of untagged embedded structs are promoted as with encoding/json: when several fields
+// share an attribute name, the least nested one wins.
+func attributeFields(t reflect.Type) []attributeField {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var (
+		fields   []attributeField
+		promoted []attributeField
+	)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct && fieldType != timeType {
+			for _, embedded := range attributeFields(fieldType) {
+				embedded.index = append([]int{i}, embedded.index...)
+				promoted = append(promoted, embedded)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, attributeField{
+			name:      name,
+			goName:    field.Name,
+			index:     []int{i},
+			typ:       field.Type,
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+
+	sort.SliceStable(promoted, func(i, j int) bool {
+		return len(promoted[i].index) < len(promoted[j].index)
+	})
+	for _, embedded := range promoted {
+		if !slices.ContainsFunc(fields, func(f attributeField) bool { return f.name == embedded.name }) {
+			fields = append(fields, embedded)
+		}
+	}
+	return fields
+}
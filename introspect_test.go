@@ -0,0 +1,53 @@
+package jsonapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelationshipNames(t *testing.T) {
+	assert.Equal(t, []string{"author", "tags"}, RelationshipNames(Article{}))
+	assert.Equal(t, []string{"author", "comments", "tags"}, RelationshipNames(&exampleArticle{}))
+	assert.Nil(t, RelationshipNames(User{}))
+	assert.Nil(t, RelationshipNames(nil))
+}
+
+func TestAttributeNames(t *testing.T) {
+	assert.Equal(t, []string{"id", "name"}, AttributeNames(User{}))
+	assert.Equal(t, []string{"content", "id", "title"}, AttributeNames(&Article{}))
+	assert.Equal(t, []string{"bio", "handle", "links"}, AttributeNames(profile{}))
+	assert.Equal(t, []string{"firstName", "fullName", "lastName"}, AttributeNames(computedUser{}))
+	assert.Nil(t, AttributeNames(nil))
+
+	t.Run("embedded structs", func(t *testing.T) {
+		type Timestamps struct {
+			CreatedAt string `json:"createdAt"`
+		}
+		type post struct {
+			EmbeddedBase
+			Timestamps
+			Title string
+		}
+		assert.Equal(t, []string{"Title", "createdAt"}, AttributeNames(post{}))
+	})
+
+	t.Run("outer fields shadow promoted fields", func(t *testing.T) {
+		type base struct {
+			Name  string `json:"name"`
+			Notes string `json:"notes"`
+		}
+		type record struct {
+			base
+			Name string `json:"name"`
+		}
+
+		fields := attributeFields(reflect.TypeOf(record{}))
+		require.Len(t, fields, 2)
+		assert.Equal(t, []int{1}, fields[0].index)
+		assert.Equal(t, []int{0, 1}, fields[1].index)
+		assert.Equal(t, []string{"name", "notes"}, AttributeNames(record{}))
+	})
+}
@@ -1,8 +1,16 @@
 package jsonapi
 
 import (
+	"cmp"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
 	"reflect"
+	"slices"
+	"sort"
+	"strings"
 )
 
 // ResourceIdentifier defines the interface that all JSON:API resources must implement
@@ -28,6 +36,14 @@ type MetaMarshaler interface {
 	MarshalMeta() map[string]interface{}
 }
 
+// AttributesMarshaler defines the interface for resources that provide computed attributes,
+// such as values derived from getter methods, during marshaling operations.
+type AttributesMarshaler interface {
+	// MarshalAttributes returns attributes to merge into the resource's attributes object.
+	// Values take precedence over attributes of the same name marshaled from struct fields.
+	MarshalAttributes() map[string]interface{}
+}
+
 // RelationshipMarshaler defines the interface for resources that have relationships
 // with other resources and can provide relationship information during marshaling.
 type RelationshipMarshaler interface {
@@ -65,6 +81,18 @@ func ManyRef[T ResourceIdentifier](refs ...T) []ResourceIdentifier {
 	return items
 }
 
+// ManyRefPtr creates a slice of [ResourceIdentifier] from a pointer to a slice of references.
+// It is the pointer-to-slice counterpart of [ManyRef] for resources that store a to-many
+// relationship in a field such as *[]Tag. A nil pointer returns nil, which marshals as
+// an empty to-many relationship.
+// This is useful when implementing the [RelationshipMarshaler.MarshalRef] method.
+func ManyRefPtr[T ResourceIdentifier](refs *[]T) []ResourceIdentifier {
+	if refs == nil {
+		return nil
+	}
+	return ManyRef(*refs...)
+}
+
 // RelationshipLinksMarshaler defines the interface for resources that can provide
 // links specific to their relationships during marshaling.
 type RelationshipLinksMarshaler interface {
@@ -81,6 +109,28 @@ type RelationshipMetaMarshaler interface {
 	MarshalRefMeta(name string) map[string]interface{}
 }
 
+// RelatedResourcesMarshaler defines the interface for resources that can provide the
+// full related resources of a relationship for inclusion in the document. Without it,
+// the identifiers returned by [RelationshipMarshaler.MarshalRef] are included as-is,
+// which is not sufficient when they only carry a type and ID.
+type RelatedResourcesMarshaler interface {
+	RelationshipMarshaler
+	// RelatedResources returns the related resources to include for the specified relationship.
+	// Returning nil falls back to the identifiers returned by MarshalRef.
+	RelatedResources(name string) []ResourceIdentifier
+}
+
+// VirtualRelationshipsMarshaler defines the interface for resources that provide relationships
+// computed at marshal time rather than declared by [RelationshipMarshaler], such as derived
+// relationships. The relationships are added to the resource's relationships object as-is;
+// their related resources are not included in the document.
+type VirtualRelationshipsMarshaler interface {
+	ResourceIdentifier
+	// MarshalVirtualRelationships returns computed relationships keyed by relationship name.
+	// Relationships declared by [RelationshipMarshaler] take precedence over entries of the same name.
+	MarshalVirtualRelationships() map[string]*Relationship
+}
+
 // RelationType represents the type of relationship between resources.
 type RelationType int
 
@@ -94,13 +144,129 @@ const (
 // It accepts single implementations of [ResourceIdentifier], slices,
 // nil values, or [Document] instances and returns a properly formatted JSON:API document
 // with optional configuration.
+//
+// The attributes of a resource are its JSON encoding, so json struct tags apply as usual.
+// Note that omitempty never omits a nested struct attribute such as an Address; use
+// omitzero, or a pointer field with omitempty, to leave out an attribute whose fields
+// are all empty.
+//
+// Empty to-one relationships are emitted with null resource linkage, {"data":null}, and
+// empty to-many relationships with an empty array, {"data":[]}, in primary and included
+// resources alike. See [WithOmitEmptyRelationships] to leave them out.
 func Marshal(data interface{}, opts ...Options) ([]byte, error) {
 	options := applyOptions(opts)
 	doc, err := marshalValue(data, &options)
 	if err != nil {
 		return nil, err
 	}
-	return jsonMarshal(doc)
+	return options.encode(doc)
+}
+
+// marshalerContextKey is used as a key for storing a [MarshalFunc] in context.Context.
+type marshalerContextKey struct{}
+
+// ContextWithMarshaler returns a copy of ctx that carries fn as the document marshaler
+// used by [MarshalWithContext]. This allows middleware to select an encoder per request.
+func ContextWithMarshaler(ctx context.Context, fn MarshalFunc) context.Context {
+	return context.WithValue(ctx, marshalerContextKey{}, fn)
+}
+
+// MarshalWithContext is like [Marshal], but encodes the document with the marshaler stored
+// in ctx by [ContextWithMarshaler], if any. An explicit [WithMarshaler] option takes precedence.
+//
+// Example:
+//
+//	ctx := jsonapi.ContextWithMarshaler(r.Context(), sonic.Marshal)
+//	data, err := jsonapi.MarshalWithContext(ctx, article)
+func MarshalWithContext(ctx context.Context, data interface{}, opts ...Options) ([]byte, error) {
+	if fn, ok := ctx.Value(marshalerContextKey{}).(MarshalFunc); ok && fn != nil {
+		opts = append([]Options{WithMarshaler(fn)}, opts...)
+	}
+	return Marshal(data, opts...)
+}
+
+// WriteTo marshals data into a JSON:API document and writes it to w, returning the number
+// of bytes written. It is useful outside of HTTP handlers, such as when writing documents
+// to files or pipes. See [Marshal].
+//
+// Example:
+//
+//	f, _ := os.Create("articles.json")
+//	defer f.Close()
+//	_, err := jsonapi.WriteTo(f, articles)
+func WriteTo(w io.Writer, data interface{}, opts ...Options) (int64, error) {
+	out, err := Marshal(data, opts...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(out)
+	return int64(n), err
+}
+
+// MarshalWrite marshals data into a JSON:API document and writes the encoded document to w
+// with a [json.Encoder], instead of returning it as a byte slice. The document is still
+// encoded in memory before it is written. The marshaler selected by [WithMarshaler] or
+// [ContextWithMarshaler], or the package marshaler configured with [SetJSONMarshaler], is
+// used instead when present, so that the output matches [Marshal]. See [Marshal].
+//
+// Example:
+//
+//	err := jsonapi.MarshalWrite(r.Context(), w, articles)
+func MarshalWrite(ctx context.Context, w io.Writer, data interface{}, opts ...Options) error {
+	if fn, ok := ctx.Value(marshalerContextKey{}).(MarshalFunc); ok && fn != nil {
+		opts = append([]Options{WithMarshaler(fn)}, opts...)
+	}
+	options := applyOptions(opts)
+	doc, err := marshalValue(data, &options)
+	if err != nil {
+		return err
+	}
+	_, err = encodeTo(w, doc, &options)
+	return err
+}
+
+// encodeTo writes the encoded document to w, streaming it unless a marshaler is configured.
+// It returns the number of bytes written.
+func encodeTo(w io.Writer, doc *Document, options *options) (int, error) {
+	cw := &countingWriter{w: w}
+	if options.marshal != nil || jsonMarshalSet {
+		out, err := options.encode(doc)
+		if err != nil {
+			return 0, err
+		}
+		return cw.Write(out)
+	}
+	err := json.NewEncoder(cw).Encode(doc)
+	return cw.n, err
+}
+
+// countingWriter is an [io.Writer] that counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
+
+// MarshalMany marshals a slice of resources into a JSON:API collection document.
+// It is a typed convenience over [Marshal] that guarantees collection output:
+// a single-element slice produces a one-element data array, and a nil slice
+// produces an empty data array.
+//
+// Example:
+//
+//	data, err := jsonapi.MarshalMany(articles, jsonapi.WithTopMeta("total", len(articles)))
+func MarshalMany[T ResourceIdentifier](items []T, opts ...Options) ([]byte, error) {
+	options := applyOptions(opts)
+	doc, err := marshalMany(items, &options)
+	if err != nil {
+		return nil, err
+	}
+	return options.encode(doc)
 }
 
 // MarshalRef marshals a specific relationship from a resource into a JSON:API document.
@@ -120,6 +286,11 @@ func Marshal(data interface{}, opts ...Options) ([]byte, error) {
 //	data, err := jsonapi.MarshalRef(article, "tags",
 //		jsonapi.WithDefaultLinks("https://api.example.com"))
 //
+// MarshalRef is also used to echo a relationship back to the client after a
+// PATCH /articles/1/relationships/tags request has been applied with [UnmarshalRef].
+// An empty to-one relationship produces {"data":null}, and an empty to-many
+// relationship produces {"data":[]}.
+//
 // The relationship must be defined in the resource's [RelationshipMarshaler.Relationships] method,
 // otherwise an error is returned.
 func MarshalRef(data RelationshipMarshaler, name string, opts ...Options) ([]byte, error) {
@@ -135,7 +306,9 @@ func MarshalRef(data RelationshipMarshaler, name string, opts ...Options) ([]byt
 		return nil, fmt.Errorf("relationship %s not found", name)
 	}
 
-	if err := marshalRelationship(data, name, refType, relationship, 0, &options); err != nil {
+	// the linkage is the primary data of a relationship document, so it is never omitted.
+	options.omitLinkage = false
+	if err := marshalRelationship(data, name, refType, relationship, 0, "", &options); err != nil {
 		return nil, fmt.Errorf("relationship %s: %w", name, err)
 	}
 
@@ -144,7 +317,42 @@ func MarshalRef(data RelationshipMarshaler, name string, opts ...Options) ([]byt
 	if err != nil {
 		return nil, err
 	}
-	return jsonMarshal(finalDoc)
+	return options.encode(finalDoc)
+}
+
+// MarshalIdentifiers marshals resource identifiers into a linkage document whose primary
+// data is an array of resource identifier objects, without attributes or relationships.
+// It is the counterpart of [UnmarshalIdentifiers] and is useful for relationship collections
+// built from identifier values. [Ref] values and identifiers implementing [MetaMarshaler]
+// carry their meta.
+// Use [Marshal] instead to marshal a []ResourceIdentifier as full resource objects.
+//
+// Example:
+//
+//	data, err := jsonapi.MarshalIdentifiers([]jsonapi.ResourceIdentifier{
+//		jsonapi.Ref{Type: "tags", ID: "1"},
+//		jsonapi.Ref{Type: "tags", ID: "2"},
+//	})
+//	// {"data":[{"type":"tags","id":"1"},{"type":"tags","id":"2"}]}
+func MarshalIdentifiers(ids []ResourceIdentifier, opts ...Options) ([]byte, error) {
+	options := applyOptions(opts)
+	doc := &Document{Data: &DocumentData{isMany: true}}
+	for _, id := range ids {
+		ref, err := marshalLinkage(id, &options)
+		if err != nil {
+			return nil, err
+		}
+		if r, ok := id.(Ref); ok && ref.Meta == nil {
+			ref.Meta = r.Meta
+		}
+		doc.Data.many = append(doc.Data.many, Resource{ID: ref.ID, Type: ref.Type, Meta: ref.Meta})
+	}
+
+	finalDoc, err := marshalDocument(doc, &options)
+	if err != nil {
+		return nil, err
+	}
+	return options.encode(finalDoc)
 }
 
 // marshalValue determines the type of data being marshaled and delegates
@@ -181,7 +389,7 @@ func marshalOne(data any, options *options) (*Document, error) {
 		res = Resource{}
 	)
 
-	if err := marshalResource(id, &res, 0, options); err != nil {
+	if err := marshalResource(id, &res, 0, "", options); err != nil {
 		return nil, err
 	}
 	doc.Data = &DocumentData{one: res}
@@ -200,7 +408,7 @@ func marshalMany(data any, options *options) (*Document, error) {
 		k := val.Index(idx).Interface()
 		if id, ok := k.(ResourceIdentifier); !ok {
 			return nil, fmt.Errorf("all elements within the slice must implement ResourceIdentifier")
-		} else if err := marshalResource(id, &res[idx], 0, options); err != nil {
+		} else if err := marshalResource(id, &res[idx], 0, "", options); err != nil {
 			return nil, err
 		}
 	}
@@ -211,38 +419,206 @@ func marshalMany(data any, options *options) (*Document, error) {
 
 // marshalDocument applies top-level document options and finalizes the document structure.
 func marshalDocument(doc *Document, options *options) (*Document, error) {
+	// merge into copies so that members of a caller's document survive, and are never modified.
 	if len(options.topLinks) > 0 {
-		doc.Links = options.topLinks
+		links := make(map[string]Link, len(doc.Links)+len(options.topLinks))
+		maps.Copy(links, doc.Links)
+		maps.Copy(links, options.topLinks)
+		doc.Links = links
 	}
 	if len(options.topMeta) > 0 {
-		doc.Meta = options.topMeta
+		meta := make(map[string]interface{}, len(doc.Meta)+len(options.topMeta))
+		maps.Copy(meta, doc.Meta)
+		maps.Copy(meta, options.topMeta)
+		doc.Meta = meta
+	}
+	if options.countData && doc.Data != nil && doc.Data.isMany {
+		meta := make(map[string]interface{}, len(doc.Meta)+1)
+		maps.Copy(meta, doc.Meta)
+		meta["count"] = len(doc.Data.many)
+		doc.Meta = meta
+	}
+	if options.paginator != nil {
+		for key, link := range options.paginator.PaginationLinks(doc) {
+			if doc.Links == nil {
+				doc.Links = make(map[string]Link)
+			}
+			doc.Links[key] = link
+		}
 	}
 	if len(options.errors) > 0 {
 		doc.Errors = options.errors
 	}
-
-	if doc.Included != nil {
-		for _, res := range doc.Included {
-			doc.Included = append(doc.Included, res)
+	if options.errorLess != nil {
+		doc.Errors = slices.Clone(doc.Errors)
+		sort.SliceStable(doc.Errors, func(i, j int) bool {
+			return options.errorLess(*doc.Errors[i], *doc.Errors[j])
+		})
+	}
+	if options.errorTitle != nil {
+		for _, err := range doc.Errors {
+			if status, ok := options.errorStatus[err]; ok {
+				err.Title = options.errorTitle(status)
+			}
 		}
 	}
+	if doc.JSONAPI == nil && options.version != "" {
+		doc.JSONAPI = &JSONAPIObject{Version: options.version}
+	}
+
+	if err := marshalIncluded(doc, options); err != nil {
+		return nil, err
+	}
+
+	if options.inspector != nil {
+		options.inspector(doc)
+	}
 
 	return doc, nil
 }
 
+// marshalIncluded appends the related resources collected during marshaling to the
+// included array of the document, skipping resources that are part of the primary data.
+// It also enforces the maximum number of included resources, if configured.
+func marshalIncluded(doc *Document, options *options) error {
+	primary := make(map[string]bool)
+	if doc.Data != nil {
+		primary[resourceUID(Ref{ID: doc.Data.one.ID, Type: doc.Data.one.Type})] = true
+		for _, res := range doc.Data.many {
+			primary[resourceUID(Ref{ID: res.ID, Type: res.Type})] = true
+		}
+	}
+
+	for _, uid := range options.includeOrder {
+		if !primary[uid] {
+			doc.Included = append(doc.Included, options.includes[uid])
+		}
+	}
+
+	if err := sortIncluded(doc.Included, options.includedSort); err != nil {
+		return err
+	}
+
+	total := len(doc.Included)
+	if options.maxIncluded <= 0 || total <= options.maxIncluded {
+		return nil
+	}
+	if options.maxIncludedErr {
+		return fmt.Errorf("included resources exceed maximum of %d: found %d", options.maxIncluded, total)
+	}
+
+	doc.Included = doc.Included[:options.maxIncluded]
+	if doc.Meta == nil {
+		doc.Meta = make(map[string]interface{})
+	}
+	doc.Meta["included"] = map[string]interface{}{
+		"total": total,
+		"limit": options.maxIncluded,
+	}
+	return nil
+}
+
+// sortIncluded orders the included resources of each type in sorts by their attributes,
+// leaving resources of other types in place.
+func sortIncluded(included []*Resource, sorts map[string][]string) error {
+	for resourceType, fields := range sorts {
+		var (
+			positions  []int
+			resources  []*Resource
+			attributes []map[string]interface{}
+		)
+		for i, res := range included {
+			if res.Type != resourceType {
+				continue
+			}
+			var attrs map[string]interface{}
+			if len(res.Attributes) > 0 {
+				if err := jsonUnmarshal(res.Attributes, &attrs); err != nil {
+					return fmt.Errorf("sort included %s: %w", resourceType, err)
+				}
+			}
+			positions = append(positions, i)
+			resources = append(resources, res)
+			attributes = append(attributes, attrs)
+		}
+
+		order := make([]int, len(resources))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			for _, field := range fields {
+				name, desc := strings.CutPrefix(field, "-")
+				c := compareAttributes(attributes[order[a]][name], attributes[order[b]][name])
+				if c != 0 {
+					return (c < 0) != desc
+				}
+			}
+			return false
+		})
+
+		for i, pos := range positions {
+			included[pos] = resources[order[i]]
+		}
+	}
+	return nil
+}
+
+// compareAttributes compares two decoded attribute values. Numbers compare numerically,
+// other values compare by their string form, and missing values sort first.
+func compareAttributes(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if x, ok := a.(float64); ok {
+		if y, ok := b.(float64); ok {
+			return cmp.Compare(x, y)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
 // marshalResource marshals a single resource identifier into a Resource object,
 // including its attributes, links, metadata, and relationships.
-func marshalResource(id ResourceIdentifier, res *Resource, depth int, options *options) error {
+func marshalResource(id ResourceIdentifier, res *Resource, depth int, path string, options *options) error {
 	res.ID = id.ResourceID()
 	res.Type = id.ResourceType()
+	res.emptyRelationships = options.emptyRefsObject
+	if res.Type == "" && options.requireType {
+		return fmt.Errorf("resource %T has no type", id)
+	}
 
 	attributes, err := jsonMarshal(id)
 	if err != nil {
 		return err
 	}
 
+	if options.timeFormat != "" {
+		if attributes, err = formatTimes(attributes, id, options.timeFormat); err != nil {
+			return err
+		}
+	}
+
+	if marshaler, ok := id.(AttributesMarshaler); ok {
+		if attributes, err = mergeAttributes(attributes, marshaler.MarshalAttributes()); err != nil {
+			return err
+		}
+	}
+
 	res.Attributes = attributes
 
+	fields, sparse := options.sparseFields[res.Type]
+	if sparse {
+		if res.Attributes, err = sparseAttributes(attributes, fields); err != nil {
+			return err
+		}
+	}
+
 	if marshaler, ok := id.(LinksMarshaler); ok {
 		res.Links = marshaler.MarshalLinks()
 	}
@@ -262,13 +638,46 @@ func marshalResource(id ResourceIdentifier, res *Resource, depth int, options *o
 	}
 
 	if marshaler, ok := id.(RelationshipMarshaler); ok {
+		var (
+			defs  = marshaler.Relationships()
+			names = make([]string, 0, len(defs))
+		)
+		for name := range defs {
+			names = append(names, name)
+		}
+		// visit relationships in a stable order so included resources are deterministic.
+		sort.Strings(names)
+
 		res.Relationships = make(map[string]*Relationship)
-		for name, reftype := range marshaler.Relationships() {
+		for _, name := range names {
+			key := options.relationshipKey(name)
+			if sparse && !slices.Contains(fields, key) {
+				continue
+			}
 			rel := &Relationship{}
-			res.Relationships[name] = rel
-			if err := marshalRelationship(marshaler, name, reftype, rel, depth, options); err != nil {
+			res.Relationships[key] = rel
+			if err := marshalRelationship(marshaler, name, defs[name], rel, depth, path, options); err != nil {
 				return err
 			}
+			if options.omitEmptyRefs && rel.isEmpty() {
+				delete(res.Relationships, key)
+			}
+		}
+	}
+
+	if marshaler, ok := id.(VirtualRelationshipsMarshaler); ok {
+		for name, rel := range marshaler.MarshalVirtualRelationships() {
+			key := options.relationshipKey(name)
+			if rel == nil || (sparse && !slices.Contains(fields, key)) {
+				continue
+			}
+			if _, exists := res.Relationships[key]; exists {
+				continue
+			}
+			if res.Relationships == nil {
+				res.Relationships = make(map[string]*Relationship)
+			}
+			res.Relationships[key] = rel
 		}
 	}
 
@@ -277,7 +686,7 @@ func marshalResource(id ResourceIdentifier, res *Resource, depth int, options *o
 
 // marshalRelationship marshals a single relationship, including its data, links, and metadata.
 // It also handles included resources based on the current depth and options.
-func marshalRelationship(id RelationshipMarshaler, name string, refType RelationType, res *Relationship, depth int, options *options) error {
+func marshalRelationship(id RelationshipMarshaler, name string, refType RelationType, res *Relationship, depth int, path string, options *options) error {
 	if marshaler, ok := id.(RelationshipLinksMarshaler); ok {
 		res.Links = marshaler.MarshalRefLinks(name)
 	}
@@ -287,7 +696,7 @@ func marshalRelationship(id RelationshipMarshaler, name string, refType Relation
 	}
 
 	for key, resolver := range options.linkResolver {
-		if link, ok := resolver.ResolveRelationshipLink(key, name, id); ok {
+		if link, ok := resolver.ResolveRelationshipLink(key, options.relationshipKey(name), id); ok {
 			res.Links[key] = link
 		}
 	}
@@ -295,36 +704,57 @@ func marshalRelationship(id RelationshipMarshaler, name string, refType Relation
 	if marshaler, ok := id.(RelationshipMetaMarshaler); ok {
 		res.Meta = marshaler.MarshalRefMeta(name)
 	}
+	if path != "" {
+		path += "."
+	}
+	path += options.relationshipKey(name)
+
+	include := depth < options.maxIncludeDepth && (options.includePaths == nil || options.includePaths[path])
 	if refType == RelationLinksOnly {
 		return nil
 	}
+	if options.omitLinkage && (len(res.Links) > 0 || len(res.Meta) > 0) {
+		// a relationship object must carry at least one of links, data or meta.
+		return nil
+	}
+	if options.linksOnly && !include && len(res.Links) > 0 {
+		// the links already describe the relationship, so the linkage is redundant.
+		return nil
+	}
 
 	refs := id.MarshalRef(name)
+	if refType == RelationToOne {
+		// an empty to-one relationship is still emitted as null resource linkage.
+		res.Data = &RelationshipData{}
+	}
 	if refType == RelationToOne && len(refs) > 0 {
-		var (
-			data = refs[0]
-			ref  = Ref{ID: data.ResourceID(), Type: data.ResourceType()}
-		)
-		if marshaler, ok := data.(MetaMarshaler); ok {
-			ref.Meta = marshaler.MarshalMeta()
+		ref, err := marshalLinkage(refs[0], options)
+		if err != nil {
+			return fmt.Errorf("relationship %s: %w", name, err)
 		}
-		res.Data = &RelationshipData{one: ref}
+		res.Data.one = ref
 	}
 	if refType == RelationToMany {
 		res.Data = &RelationshipData{isMany: true}
 		for _, data := range refs {
-			ref := Ref{ID: data.ResourceID(), Type: data.ResourceType()}
-			if marshaler, ok := data.(MetaMarshaler); ok {
-				ref.Meta = marshaler.MarshalMeta()
+			ref, err := marshalLinkage(data, options)
+			if err != nil {
+				return fmt.Errorf("relationship %s: %w", name, err)
 			}
 			res.Data.many = append(res.Data.many, ref)
 		}
 	}
 
-	if depth >= options.maxIncludeDepth {
+	if !include || options.omitLinkage {
 		return nil
 	}
 
+	if marshaler, ok := id.(RelatedResourcesMarshaler); ok {
+		if related := marshaler.RelatedResources(name); related != nil {
+			refs = related
+		}
+	}
+
 	for _, data := range refs {
 		uid := resourceUID(data)
 		if _, exists := options.includes[uid]; exists {
@@ -332,14 +762,78 @@ func marshalRelationship(id RelationshipMarshaler, name string, refType Relation
 		}
 		res := &Resource{}
 		options.includes[uid] = res
-		if err := marshalResource(data, res, depth+1, options); err != nil {
+		options.includeOrder = append(options.includeOrder, uid)
+		if err := marshalResource(data, res, depth+1, path, options); err != nil {
 			return err
 		}
+		if options.includedMeta == nil {
+			continue
+		}
+		if extra := options.includedMeta(path, data); extra != nil {
+			// copy the meta so the resource's own map is never modified.
+			meta := make(map[string]interface{}, len(res.Meta)+len(extra))
+			maps.Copy(meta, res.Meta)
+			maps.Copy(meta, extra)
+			res.Meta = meta
+		}
 	}
 
 	return nil
 }
 
+// marshalLinkage creates the resource identifier object for a related resource.
+func marshalLinkage(data ResourceIdentifier, options *options) (Ref, error) {
+	ref := Ref{ID: data.ResourceID(), Type: data.ResourceType()}
+	if ref.Type == "" && options.requireType {
+		return ref, fmt.Errorf("related resource %T has no type", data)
+	}
+	if marshaler, ok := data.(MetaMarshaler); ok {
+		ref.Meta = marshaler.MarshalMeta()
+	}
+	return ref, nil
+}
+
+// mergeAttributes merges the computed attributes into the serialized attributes object.
+func mergeAttributes(attributes []byte, computed map[string]interface{}) ([]byte, error) {
+	if len(computed) == 0 {
+		return attributes, nil
+	}
+
+	// keep marshaled attributes as raw JSON so their encoding is preserved exactly.
+	raw := make(map[string]json.RawMessage)
+	if err := jsonUnmarshal(attributes, &raw); err != nil {
+		return nil, fmt.Errorf("merge computed attributes: %w", err)
+	}
+
+	all := make(map[string]interface{}, len(raw)+len(computed))
+	for key, value := range raw {
+		all[key] = value
+	}
+	maps.Copy(all, computed)
+	return jsonMarshal(all)
+}
+
+// sparseAttributes filters the serialized attributes object down to the provided fields.
+// It returns nil if none of the attributes are part of the fieldset.
+func sparseAttributes(attributes []byte, fields []string) ([]byte, error) {
+	all := make(map[string]json.RawMessage)
+	if err := jsonUnmarshal(attributes, &all); err != nil {
+		return nil, fmt.Errorf("apply sparse fieldset: %w", err)
+	}
+
+	sparse := make(map[string]json.RawMessage)
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			sparse[field] = value
+		}
+	}
+
+	if len(sparse) == 0 {
+		return nil, nil
+	}
+	return jsonMarshal(sparse)
+}
+
 // resourceUID generates a unique identifier for a resource based on its type and ID.
 func resourceUID(id ResourceIdentifier) string {
 	return id.ResourceType() + ":" + id.ResourceID()
@@ -1,10 +1,16 @@
 package jsonapi
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type testResource struct {
@@ -122,3 +128,974 @@ func TestMarshalMany(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, doc.Data)
 }
+
+func TestMarshalRef_EchoRelationshipDocument(t *testing.T) {
+	t.Run("to-many relationship after patch", func(t *testing.T) {
+		article := Article{ID: "1", TagIDs: []string{"9"}}
+		body := `{"data":[{"type":"tags","id":"1"},{"type":"tags","id":"2"}]}`
+
+		article.TagIDs = nil
+		err := UnmarshalRef([]byte(body), "tags", &article)
+		assert.NoError(t, err)
+
+		data, err := MarshalRef(article, "tags", WithoutJSONAPIObject())
+		assert.NoError(t, err)
+		assert.JSONEq(t, body, string(data))
+	})
+
+	t.Run("with relationship links", func(t *testing.T) {
+		article := Article{ID: "1", TagIDs: []string{"1"}}
+
+		data, err := MarshalRef(article, "tags", WithDefaultLinks("http://example.com"))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"jsonapi": {"version": "1.1"},
+			"links": {
+				"self": "http://example.com/articles/1/relationships/tags",
+				"related": "http://example.com/articles/1/tags"
+			},
+			"data": [{"type":"tags","id":"1"}]
+		}`, string(data))
+	})
+
+	t.Run("empty to-many relationship", func(t *testing.T) {
+		article := Article{ID: "1"}
+
+		data, err := MarshalRef(article, "tags")
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"jsonapi":{"version":"1.1"},"data":[]}`, string(data))
+	})
+
+	t.Run("cleared to-one relationship", func(t *testing.T) {
+		article := Article{ID: "1", AuthorID: "1"}
+
+		err := UnmarshalRef([]byte(`{"data":null}`), "author", &article)
+		assert.NoError(t, err)
+
+		data, err := MarshalRef(article, "author")
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"jsonapi":{"version":"1.1"},"data":null}`, string(data))
+	})
+}
+
+// featuredPost has a to-one relationship to a full [Article], which is included.
+type featuredPost struct {
+	ID       string  `json:"-"`
+	Featured Article `json:"-"`
+}
+
+func (p featuredPost) ResourceID() string   { return p.ID }
+func (p featuredPost) ResourceType() string { return "posts" }
+
+func (p featuredPost) Relationships() map[string]RelationType {
+	return map[string]RelationType{"featured": RelationToOne}
+}
+
+func (p featuredPost) MarshalRef(name string) []ResourceIdentifier {
+	if name == "featured" && p.Featured.ID != "" {
+		return []ResourceIdentifier{p.Featured}
+	}
+	return nil
+}
+
+func TestMarshal_EmptyToOneLinkage(t *testing.T) {
+	relationships := func(t *testing.T, resource map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		rels, ok := resource["relationships"].(map[string]interface{})
+		require.True(t, ok)
+		return rels
+	}
+	decode := func(t *testing.T, data []byte) map[string]interface{} {
+		t.Helper()
+		var doc map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &doc))
+		return doc
+	}
+
+	t.Run("primary resource", func(t *testing.T) {
+		data, err := Marshal(Article{ID: "1"})
+		require.NoError(t, err)
+		rels := relationships(t, decode(t, data)["data"].(map[string]interface{}))
+		assert.Equal(t, map[string]interface{}{"data": nil}, rels["author"])
+		assert.Equal(t, map[string]interface{}{"data": []interface{}{}}, rels["tags"])
+	})
+
+	t.Run("collection", func(t *testing.T) {
+		data, err := MarshalMany([]Article{{ID: "1"}, {ID: "2", AuthorID: "1"}})
+		require.NoError(t, err)
+		primary := decode(t, data)["data"].([]interface{})
+		require.Len(t, primary, 2)
+		assert.Equal(t, map[string]interface{}{"data": nil}, relationships(t, primary[0].(map[string]interface{}))["author"])
+		assert.NotNil(t, relationships(t, primary[1].(map[string]interface{}))["author"].(map[string]interface{})["data"])
+	})
+
+	t.Run("included resource", func(t *testing.T) {
+		data, err := Marshal(featuredPost{ID: "1", Featured: Article{ID: "2", Title: "Hello"}})
+		require.NoError(t, err)
+		included := decode(t, data)["included"].([]interface{})
+		require.Len(t, included, 1)
+		assert.Equal(t, map[string]interface{}{"data": nil}, relationships(t, included[0].(map[string]interface{}))["author"])
+	})
+
+	t.Run("empty relationship of primary resource", func(t *testing.T) {
+		data, err := Marshal(featuredPost{ID: "1"})
+		require.NoError(t, err)
+		rels := relationships(t, decode(t, data)["data"].(map[string]interface{}))
+		assert.Equal(t, map[string]interface{}{"data": nil}, rels["featured"])
+	})
+}
+
+func TestMarshal_JSONAPIObject(t *testing.T) {
+	resource := testResource{ID: "1", Name: "test"}
+
+	t.Run("emitted by default", func(t *testing.T) {
+		data, err := Marshal(resource)
+		assert.NoError(t, err)
+
+		var doc Document
+		err = json.Unmarshal(data, &doc)
+		assert.NoError(t, err)
+		if assert.NotNil(t, doc.JSONAPI) {
+			assert.Equal(t, Version, doc.JSONAPI.Version)
+		}
+	})
+
+	t.Run("custom version", func(t *testing.T) {
+		data, err := Marshal(resource, DocumentJSONAPIVersion("1.0"))
+		assert.NoError(t, err)
+
+		var doc Document
+		err = json.Unmarshal(data, &doc)
+		assert.NoError(t, err)
+		if assert.NotNil(t, doc.JSONAPI) {
+			assert.Equal(t, "1.0", doc.JSONAPI.Version)
+		}
+	})
+
+	t.Run("opt out", func(t *testing.T) {
+		data, err := Marshal(resource, WithoutJSONAPIObject())
+		assert.NoError(t, err)
+
+		var raw map[string]json.RawMessage
+		err = json.Unmarshal(data, &raw)
+		assert.NoError(t, err)
+		assert.NotContains(t, raw, "jsonapi")
+	})
+
+	t.Run("preserves document member", func(t *testing.T) {
+		doc := &Document{JSONAPI: &JSONAPIObject{Version: "1.0", Meta: map[string]interface{}{"build": "abc"}}}
+		data, err := Marshal(doc)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"jsonapi":{"version":"1.0","meta":{"build":"abc"}}}`, string(data))
+	})
+
+	t.Run("round trips through unmarshal", func(t *testing.T) {
+		data, err := Marshal(resource, DocumentJSONAPIVersion("1.0"))
+		require.NoError(t, err)
+
+		var doc Document
+		require.NoError(t, Unmarshal(data, &doc))
+		if assert.NotNil(t, doc.JSONAPI) {
+			assert.Equal(t, "1.0", doc.JSONAPI.Version)
+		}
+
+		var target testResource
+		require.NoError(t, Unmarshal(data, &target))
+		assert.Equal(t, "test", target.Name)
+	})
+}
+
+func TestMarshal_NilAndEmptySlices(t *testing.T) {
+	var nilSlice []testResource
+
+	tests := []struct {
+		name  string
+		input interface{}
+	}{
+		{name: "typed nil slice", input: nilSlice},
+		{name: "empty slice", input: []testResource{}},
+		{name: "nil slice of pointers", input: []*Article(nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Marshal(tt.input, WithoutJSONAPIObject())
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"data":[]}`, string(data))
+		})
+	}
+}
+
+type pointerSliceArticle struct {
+	ID   string `json:"-"`
+	Tags *[]Tag `json:"-"`
+}
+
+func (a pointerSliceArticle) ResourceID() string   { return a.ID }
+func (a pointerSliceArticle) ResourceType() string { return "articles" }
+func (a pointerSliceArticle) Relationships() map[string]RelationType {
+	return map[string]RelationType{"tags": RelationToMany}
+}
+func (a pointerSliceArticle) MarshalRef(name string) []ResourceIdentifier {
+	if name == "tags" {
+		return ManyRefPtr(a.Tags)
+	}
+	return nil
+}
+
+func TestManyRefPtr(t *testing.T) {
+	assert.Nil(t, ManyRefPtr[Tag](nil))
+	assert.Equal(t, []ResourceIdentifier{}, ManyRefPtr(&[]Tag{}))
+	assert.Equal(t, []ResourceIdentifier{Tag{ID: "1"}}, ManyRefPtr(&[]Tag{{ID: "1"}}))
+}
+
+func TestMarshal_PointerToSliceRelationship(t *testing.T) {
+	t.Run("populated pointer to slice", func(t *testing.T) {
+		tags := []Tag{{ID: "1", Name: "go"}, {ID: "2", Name: "json"}}
+		data, err := Marshal(pointerSliceArticle{ID: "1", Tags: &tags})
+		assert.NoError(t, err)
+
+		var doc Document
+		assert.NoError(t, json.Unmarshal(data, &doc))
+		rel := doc.Data.one.Relationships["tags"]
+		if assert.NotNil(t, rel) && assert.NotNil(t, rel.Data) {
+			assert.Equal(t, []Ref{{Type: "tags", ID: "1"}, {Type: "tags", ID: "2"}}, rel.Data.many)
+		}
+		assert.Len(t, doc.Included, 2)
+	})
+
+	t.Run("nil pointer to slice", func(t *testing.T) {
+		data, err := Marshal(pointerSliceArticle{ID: "1"})
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `"relationships":{"tags":{"data":[]}}`)
+	})
+}
+
+type customAttributesArticle struct {
+	ID       string
+	Title    string
+	AuthorID string
+}
+
+func (a customAttributesArticle) ResourceID() string   { return a.ID }
+func (a customAttributesArticle) ResourceType() string { return "articles" }
+
+// MarshalJSON customizes the attributes object independently of relationship marshaling.
+func (a customAttributesArticle) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"headline": strings.ToUpper(a.Title)})
+}
+
+func (a customAttributesArticle) Relationships() map[string]RelationType {
+	return map[string]RelationType{"author": RelationToOne}
+}
+
+func (a customAttributesArticle) MarshalRef(name string) []ResourceIdentifier {
+	if name == "author" {
+		return OneRef(User{ID: a.AuthorID, Name: "Jane"})
+	}
+	return nil
+}
+
+func TestMarshal_CustomAttributesWithRelationships(t *testing.T) {
+	article := customAttributesArticle{ID: "1", Title: "hello", AuthorID: "7"}
+
+	data, err := Marshal(article, WithoutJSONAPIObject())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"data": {
+			"type": "articles",
+			"id": "1",
+			"attributes": {"headline": "HELLO"},
+			"relationships": {
+				"author": {"data": {"type": "users", "id": "7"}}
+			}
+		},
+		"included": [
+			{"type": "users", "id": "7", "attributes": {"id": "7", "name": "Jane"}}
+		]
+	}`, string(data))
+}
+
+func TestMarshalMany_Generic(t *testing.T) {
+	t.Run("single element slice is a collection", func(t *testing.T) {
+		data, err := MarshalMany([]testResource{{ID: "1", Name: "test1"}})
+		assert.NoError(t, err)
+
+		var raw map[string]json.RawMessage
+		assert.NoError(t, json.Unmarshal(data, &raw))
+		assert.Equal(t, byte('['), raw["data"][0])
+
+		resources, err := UnmarshalManyAs[testResource](data)
+		assert.NoError(t, err)
+		assert.Equal(t, []testResource{{ID: "1", Name: "test1"}}, resources)
+	})
+
+	t.Run("nil slice", func(t *testing.T) {
+		data, err := MarshalMany[testResource](nil, WithoutJSONAPIObject())
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"data":[]}`, string(data))
+	})
+
+	t.Run("with options", func(t *testing.T) {
+		data, err := MarshalMany([]Article{{ID: "1", AuthorID: "1"}},
+			WithTopMeta("total", 1),
+			WithRelationshipLinkage(false),
+		)
+		assert.NoError(t, err)
+
+		var doc Document
+		assert.NoError(t, json.Unmarshal(data, &doc))
+		assert.Equal(t, float64(1), doc.Meta["total"])
+		assert.Empty(t, doc.Included)
+	})
+}
+
+// relatedArticle supplies full related resources for its author relationship.
+type relatedArticle struct {
+	Article
+}
+
+func (a relatedArticle) RelatedResources(name string) []ResourceIdentifier {
+	if name == "author" {
+		return OneRef(users[a.AuthorID])
+	}
+	return nil
+}
+
+func TestMarshal_RelatedResourcesMarshaler(t *testing.T) {
+	article := relatedArticle{Article{ID: "1", AuthorID: "2", TagIDs: []string{"1"}}}
+
+	data, err := Marshal(article)
+	assert.NoError(t, err)
+
+	var doc Document
+	assert.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "2", doc.Data.one.Relationships["author"].Data.one.ID)
+	if assert.Len(t, doc.Included, 2) {
+		assert.Equal(t, "users", doc.Included[0].Type)
+		assert.JSONEq(t, `{"id":"2","name":"Jane Smith"}`, string(doc.Included[0].Attributes))
+
+		// tags fall back to the identifiers returned by MarshalRef
+		assert.Equal(t, "tags", doc.Included[1].Type)
+		assert.JSONEq(t, `{"id":"1","name":""}`, string(doc.Included[1].Attributes))
+	}
+}
+
+func TestMarshal_HeterogeneousInterfaceSlice(t *testing.T) {
+	items := []interface{}{
+		User{ID: "1", Name: "John Doe"},
+		&Article{ID: "2", Title: "Mixed", AuthorID: "1"},
+	}
+
+	data, err := Marshal(items)
+	assert.NoError(t, err)
+
+	var doc Document
+	assert.NoError(t, json.Unmarshal(data, &doc))
+	if assert.Len(t, doc.Data.many, 2) {
+		assert.Equal(t, "users", doc.Data.many[0].Type)
+		assert.JSONEq(t, `{"id":"1","name":"John Doe"}`, string(doc.Data.many[0].Attributes))
+		assert.Empty(t, doc.Data.many[0].Relationships)
+
+		assert.Equal(t, "articles", doc.Data.many[1].Type)
+		assert.Equal(t, "Mixed", mustAttribute(t, doc.Data.many[1], "title"))
+		assert.Contains(t, doc.Data.many[1].Relationships, "author")
+	}
+
+	// the user is already primary data, so it is not repeated in included
+	assert.Empty(t, doc.Included)
+
+	_, err = Marshal([]interface{}{User{ID: "1"}, "not a resource"})
+	assert.EqualError(t, err, "all elements within the slice must implement ResourceIdentifier")
+}
+
+func mustAttribute(t *testing.T, res Resource, name string) interface{} {
+	t.Helper()
+	var attrs map[string]interface{}
+	assert.NoError(t, json.Unmarshal(res.Attributes, &attrs))
+	return attrs[name]
+}
+
+// computedUser derives its full name attribute from a getter method.
+type computedUser struct {
+	ID        string `json:"-"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+func (u computedUser) ResourceID() string   { return u.ID }
+func (u computedUser) ResourceType() string { return "users" }
+func (u computedUser) FullName() string     { return u.FirstName + " " + u.LastName }
+
+func (u computedUser) MarshalAttributes() map[string]interface{} {
+	return map[string]interface{}{"fullName": u.FullName()}
+}
+
+func TestMarshal_AttributesMarshaler(t *testing.T) {
+	user := computedUser{ID: "1", FirstName: "Jane", LastName: "Smith"}
+
+	t.Run("merges computed attributes", func(t *testing.T) {
+		data, err := Marshal(user, WithoutJSONAPIObject())
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"data": {
+				"id": "1",
+				"type": "users",
+				"attributes": {"firstName": "Jane", "lastName": "Smith", "fullName": "Jane Smith"}
+			}
+		}`, string(data))
+	})
+
+	t.Run("sparse fieldsets apply to computed attributes", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/users/1?fields[users]=fullName", nil)
+		data, err := Marshal(user, WithRequest(req))
+		assert.NoError(t, err)
+
+		var doc Document
+		assert.NoError(t, json.Unmarshal(data, &doc))
+		assert.JSONEq(t, `{"fullName":"Jane Smith"}`, string(doc.Data.one.Attributes))
+	})
+}
+
+// profile carries only json struct tags; its type and id come from the identifier methods.
+type profile struct {
+	UserID   string   `json:"-"`
+	Handle   string   `json:"handle"`
+	Bio      string   `json:"bio,omitempty"`
+	Links    []string `json:"links"`
+	internal string
+}
+
+func (p profile) ResourceID() string   { return p.UserID }
+func (p profile) ResourceType() string { return "profiles" }
+
+func TestMarshal_JSONTaggedAttributes(t *testing.T) {
+	data, err := Marshal(profile{UserID: "9", Handle: "jane", Links: []string{"https://example.com"}, internal: "x"}, WithoutJSONAPIObject())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"data": {
+			"id": "9",
+			"type": "profiles",
+			"attributes": {"handle": "jane", "links": ["https://example.com"]}
+		}
+	}`, string(data))
+}
+
+// exampleArticle mirrors the example server's article, marshaled entirely through methods.
+type exampleArticle struct {
+	Article
+}
+
+func (a exampleArticle) MarshalLinks() map[string]Link {
+	return map[string]Link{"self": {Href: "/articles/" + a.ID}}
+}
+
+func (a exampleArticle) Relationships() map[string]RelationType {
+	return map[string]RelationType{
+		"author":   RelationToOne,
+		"tags":     RelationToMany,
+		"comments": RelationLinksOnly,
+	}
+}
+
+func (a exampleArticle) MarshalRefLinks(name string) map[string]Link {
+	if name == "comments" {
+		return map[string]Link{
+			"self":    {Href: "/articles/" + a.ID + "/relationships/comments"},
+			"related": {Href: "/articles/" + a.ID + "/comments"},
+		}
+	}
+	return nil
+}
+
+func TestMarshal_InterfaceDrivenResource(t *testing.T) {
+	article := exampleArticle{Article{ID: "1", Title: "Getting Started", Content: "Body", AuthorID: "1", TagIDs: []string{"1", "2"}}}
+
+	t.Run("single resource", func(t *testing.T) {
+		data, err := Marshal(article, WithMaxIncludeDepth(0), WithoutJSONAPIObject())
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"data": {
+				"id": "1",
+				"type": "articles",
+				"attributes": {"id": "1", "title": "Getting Started", "content": "Body"},
+				"links": {"self": "/articles/1"},
+				"relationships": {
+					"author": {"data": {"id": "1", "type": "users"}},
+					"tags": {"data": [{"id": "1", "type": "tags"}, {"id": "2", "type": "tags"}]},
+					"comments": {
+						"links": {
+							"self": "/articles/1/relationships/comments",
+							"related": "/articles/1/comments"
+						}
+					}
+				}
+			}
+		}`, string(data))
+	})
+
+	t.Run("collection with included resources", func(t *testing.T) {
+		other := exampleArticle{Article{ID: "2", Title: "Relationships", AuthorID: "1", TagIDs: []string{"1"}}}
+		data, err := Marshal([]exampleArticle{article, other})
+		assert.NoError(t, err)
+
+		var doc Document
+		assert.NoError(t, json.Unmarshal(data, &doc))
+		assert.Len(t, doc.Data.many, 2)
+
+		// shared related resources are included once
+		var uids []string
+		for _, res := range doc.Included {
+			uids = append(uids, res.Type+":"+res.ID)
+		}
+		assert.Equal(t, []string{"users:1", "tags:1", "tags:2"}, uids)
+	})
+
+	t.Run("relationship document", func(t *testing.T) {
+		data, err := MarshalRef(article, "tags", WithoutJSONAPIObject())
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"data": [{"id": "1", "type": "tags"}, {"id": "2", "type": "tags"}]}`, string(data))
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		data, err := Marshal(article)
+		assert.NoError(t, err)
+
+		var decoded Article
+		assert.NoError(t, Unmarshal(data, &decoded))
+		assert.Equal(t, article.Article, decoded)
+	})
+}
+
+func TestMarshal_CollectionWithMeta(t *testing.T) {
+	articles := []Article{
+		{ID: "1", Title: "First", AuthorID: "1"},
+		{ID: "2", Title: "Second", AuthorID: "2"},
+	}
+
+	data, err := Marshal(articles, WithTopMeta("total", 2), WithTopMeta("page", 1))
+	assert.NoError(t, err)
+
+	var doc Document
+	assert.NoError(t, json.Unmarshal(data, &doc))
+	assert.Len(t, doc.Data.many, 2)
+	assert.Equal(t, map[string]interface{}{"total": float64(2), "page": float64(1)}, doc.Meta)
+
+	// included resources coexist with the top-level meta
+	if assert.Len(t, doc.Included, 2) {
+		assert.Equal(t, "users", doc.Included[0].Type)
+		assert.Equal(t, "users", doc.Included[1].Type)
+	}
+}
+
+// nullableResource distinguishes absent, null, and zero attribute values through pointers.
+type nullableResource struct {
+	ID       string `json:"-"`
+	Rating   *int   `json:"rating"`
+	Priority *int   `json:"priority,omitempty"`
+}
+
+func (r nullableResource) ResourceID() string   { return r.ID }
+func (r nullableResource) ResourceType() string { return "items" }
+
+func (r *nullableResource) SetResourceID(id string) error {
+	r.ID = id
+	return nil
+}
+
+func TestMarshal_NullableAttributes(t *testing.T) {
+	zero := 0
+
+	tests := []struct {
+		name     string
+		resource nullableResource
+		expected string
+	}{
+		{"nil pointers", nullableResource{ID: "1"}, `{"rating":null}`},
+		{"zero values", nullableResource{ID: "1", Rating: &zero, Priority: &zero}, `{"rating":0,"priority":0}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Marshal(tt.resource)
+			assert.NoError(t, err)
+
+			var doc Document
+			assert.NoError(t, json.Unmarshal(data, &doc))
+			assert.JSONEq(t, tt.expected, string(doc.Data.one.Attributes))
+
+			var decoded nullableResource
+			assert.NoError(t, Unmarshal(data, &decoded))
+			assert.Equal(t, tt.resource, decoded)
+		})
+	}
+
+	t.Run("sparse fieldsets keep explicit null", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/items/1?fields[items]=rating", nil)
+		data, err := Marshal(nullableResource{ID: "1"}, WithRequest(req))
+		assert.NoError(t, err)
+
+		var doc Document
+		assert.NoError(t, json.Unmarshal(data, &doc))
+		assert.JSONEq(t, `{"rating":null}`, string(doc.Data.one.Attributes))
+	})
+}
+
+func TestWriteTo(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := WriteTo(&buf, User{ID: "1", Name: "John Doe"}, WithoutJSONAPIObject())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	assert.JSONEq(t, `{"data":{"id":"1","type":"users","attributes":{"id":"1","name":"John Doe"}}}`, buf.String())
+
+	_, err = WriteTo(&buf, 42)
+	assert.Error(t, err)
+
+	reader, writer := io.Pipe()
+	reader.Close()
+	_, err = WriteTo(writer, User{ID: "1"})
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+}
+
+// metaUser provides resource-level meta through MetaMarshaler.
+type metaUser struct {
+	User
+}
+
+func (u metaUser) MarshalMeta() map[string]interface{} {
+	return map[string]interface{}{"lastLogin": "2024-01-01"}
+}
+
+func TestMarshal_ResourceMetaPlacement(t *testing.T) {
+	data, err := Marshal(metaUser{User{ID: "1", Name: "John Doe"}}, WithTopMeta("requestId", "abc"), WithoutJSONAPIObject())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"meta": {"requestId": "abc"},
+		"data": {
+			"id": "1",
+			"type": "users",
+			"attributes": {"id": "1", "name": "John Doe"},
+			"meta": {"lastLogin": "2024-01-01"}
+		}
+	}`, string(data))
+
+	t.Run("without document meta", func(t *testing.T) {
+		data, err := Marshal(metaUser{User{ID: "1"}})
+		assert.NoError(t, err)
+
+		var doc Document
+		assert.NoError(t, json.Unmarshal(data, &doc))
+		assert.Nil(t, doc.Meta)
+		assert.Equal(t, map[string]interface{}{"lastLogin": "2024-01-01"}, doc.Data.one.Meta)
+	})
+}
+
+func TestMarshalWithContext(t *testing.T) {
+	resource := testResource{ID: "1", Name: "test"}
+	tagged := func(tag string) MarshalFunc {
+		return func(v interface{}) ([]byte, error) {
+			return []byte(`"` + tag + `"`), nil
+		}
+	}
+
+	t.Run("uses marshaler from context", func(t *testing.T) {
+		ctx := ContextWithMarshaler(context.Background(), tagged("context"))
+		data, err := MarshalWithContext(ctx, resource)
+		require.NoError(t, err)
+		assert.Equal(t, `"context"`, string(data))
+	})
+
+	t.Run("explicit option overrides context", func(t *testing.T) {
+		ctx := ContextWithMarshaler(context.Background(), tagged("context"))
+		data, err := MarshalWithContext(ctx, resource, WithMarshaler(tagged("option")))
+		require.NoError(t, err)
+		assert.Equal(t, `"option"`, string(data))
+	})
+
+	t.Run("falls back to default marshaler", func(t *testing.T) {
+		data, err := MarshalWithContext(context.Background(), resource, WithoutJSONAPIObject())
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"data":{"id":"1","type":"test","attributes":{"ID":"1","Name":"test"}}}`, string(data))
+	})
+}
+
+// popularArticle is an [Article] with a computed "similar" relationship.
+type popularArticle struct {
+	Article
+}
+
+func (a popularArticle) MarshalVirtualRelationships() map[string]*Relationship {
+	return map[string]*Relationship{
+		"similar": {
+			Data:  ToManyData(Ref{Type: "articles", ID: "7"}, Ref{Type: "articles", ID: "8"}),
+			Links: map[string]Link{"related": {Href: "/articles/" + a.ID + "/similar"}},
+		},
+		"featured": {Data: ToOneData(nil)},
+	}
+}
+
+func TestMarshal_VirtualRelationships(t *testing.T) {
+	article := popularArticle{Article{ID: "1", Title: "Hello"}}
+
+	t.Run("adds computed relationships", func(t *testing.T) {
+		data, err := Marshal(article, WithoutJSONAPIObject())
+		require.NoError(t, err)
+
+		var doc map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &doc))
+		relationships := doc["data"].(map[string]interface{})["relationships"].(map[string]interface{})
+		assert.JSONEq(t, `{
+			"data": [{"type": "articles", "id": "7"}, {"type": "articles", "id": "8"}],
+			"links": {"related": "/articles/1/similar"}
+		}`, mustJSON(t, relationships["similar"]))
+		assert.JSONEq(t, `{"data": null}`, mustJSON(t, relationships["featured"]))
+		assert.Contains(t, relationships, "author")
+		assert.NotContains(t, doc, "included")
+	})
+
+	t.Run("respects sparse fieldsets", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/articles/1?fields[articles]=title,featured", nil)
+		data, err := Marshal(article, WithRequest(req))
+		require.NoError(t, err)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(data, &doc))
+		assert.Contains(t, doc.Data.one.Relationships, "featured")
+		assert.NotContains(t, doc.Data.one.Relationships, "similar")
+	})
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestMarshalWrite(t *testing.T) {
+	articles := []Article{{ID: "1", Title: "First"}, {ID: "2", Title: "Second"}}
+
+	t.Run("matches buffered output", func(t *testing.T) {
+		var buf strings.Builder
+		require.NoError(t, MarshalWrite(context.Background(), &buf, articles))
+
+		expected, err := Marshal(articles)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), buf.String())
+	})
+
+	t.Run("uses marshaler from context", func(t *testing.T) {
+		ctx := ContextWithMarshaler(context.Background(), func(v interface{}) ([]byte, error) {
+			return []byte(`"custom"`), nil
+		})
+		var buf strings.Builder
+		require.NoError(t, MarshalWrite(ctx, &buf, articles))
+		assert.Equal(t, `"custom"`, buf.String())
+	})
+
+	t.Run("uses package marshaler", func(t *testing.T) {
+		original := jsonMarshal
+		defer func() { jsonMarshal, jsonMarshalSet = original, false }()
+		SetJSONMarshaler(func(v interface{}) ([]byte, error) {
+			if _, ok := v.(*Document); ok {
+				return []byte(`"package"`), nil
+			}
+			return json.Marshal(v)
+		})
+
+		var buf strings.Builder
+		require.NoError(t, MarshalWrite(context.Background(), &buf, articles))
+		expected, err := Marshal(articles)
+		require.NoError(t, err)
+		assert.Equal(t, `"package"`, buf.String())
+		assert.Equal(t, string(expected), buf.String())
+	})
+
+	t.Run("reports marshaling errors", func(t *testing.T) {
+		var buf strings.Builder
+		err := MarshalWrite(context.Background(), &buf, untypedResource{ID: "1"}, WithRequireType())
+		assert.Error(t, err)
+		assert.Empty(t, buf.String())
+	})
+}
+
+// threadPost includes its comments, which carry a created_at attribute.
+type threadPost struct {
+	ID       string          `json:"-"`
+	Comments []threadComment `json:"-"`
+}
+
+func (p threadPost) ResourceID() string   { return p.ID }
+func (p threadPost) ResourceType() string { return "posts" }
+
+func (p threadPost) Relationships() map[string]RelationType {
+	return map[string]RelationType{"comments": RelationToMany}
+}
+
+func (p threadPost) MarshalRef(name string) []ResourceIdentifier {
+	return ManyRef(p.Comments...)
+}
+
+type threadComment struct {
+	ID        string `json:"-"`
+	CreatedAt string `json:"created_at"`
+}
+
+func (c threadComment) ResourceID() string   { return c.ID }
+func (c threadComment) ResourceType() string { return "comments" }
+
+func TestMarshal_IncludedSort(t *testing.T) {
+	post := threadPost{ID: "1", Comments: []threadComment{
+		{ID: "a", CreatedAt: "2024-03-01T00:00:00Z"},
+		{ID: "b", CreatedAt: "2024-01-01T00:00:00Z"},
+		{ID: "c", CreatedAt: "2024-02-01T00:00:00Z"},
+	}}
+
+	includedIDs := func(t *testing.T, opts ...Options) []string {
+		t.Helper()
+		data, err := Marshal(post, opts...)
+		require.NoError(t, err)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(data, &doc))
+		ids := make([]string, 0, len(doc.Included))
+		for _, res := range doc.Included {
+			ids = append(ids, res.ID)
+		}
+		return ids
+	}
+
+	t.Run("traversal order by default", func(t *testing.T) {
+		assert.Equal(t, []string{"a", "b", "c"}, includedIDs(t))
+	})
+
+	t.Run("ascending", func(t *testing.T) {
+		assert.Equal(t, []string{"b", "c", "a"}, includedIDs(t, WithIncludedSort("comments", "created_at")))
+	})
+
+	t.Run("descending", func(t *testing.T) {
+		assert.Equal(t, []string{"a", "c", "b"}, includedIDs(t, WithIncludedSort("comments", "-created_at")))
+	})
+
+	t.Run("other types unaffected", func(t *testing.T) {
+		assert.Equal(t, []string{"a", "b", "c"}, includedIDs(t, WithIncludedSort("users", "created_at")))
+	})
+}
+
+// annotatedArticle provides meta and links for its relationships.
+type annotatedArticle struct {
+	Article
+}
+
+func (a annotatedArticle) MarshalRefMeta(name string) map[string]interface{} {
+	if name == "tags" {
+		return map[string]interface{}{"count": len(a.TagIDs)}
+	}
+	return nil
+}
+
+func (a annotatedArticle) MarshalRefLinks(name string) map[string]Link {
+	return map[string]Link{"self": {Href: "/articles/" + a.ID + "/relationships/" + name}}
+}
+
+func TestMarshal_RelationshipMetaAndLinks(t *testing.T) {
+	article := annotatedArticle{Article{ID: "1", AuthorID: "1", TagIDs: []string{"1", "2"}}}
+
+	data, err := Marshal(article, WithMaxIncludeDepth(0), WithoutJSONAPIObject())
+	require.NoError(t, err)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	relationships := doc["data"].(map[string]interface{})["relationships"].(map[string]interface{})
+	assert.JSONEq(t, `{
+		"data": [{"type": "tags", "id": "1"}, {"type": "tags", "id": "2"}],
+		"links": {"self": "/articles/1/relationships/tags"},
+		"meta": {"count": 2}
+	}`, mustJSON(t, relationships["tags"]))
+	assert.JSONEq(t, `{
+		"data": {"type": "users", "id": "1"},
+		"links": {"self": "/articles/1/relationships/author"}
+	}`, mustJSON(t, relationships["author"]))
+}
+
+func TestMarshalIdentifiers(t *testing.T) {
+	t.Run("identifier-only data array", func(t *testing.T) {
+		data, err := MarshalIdentifiers([]ResourceIdentifier{
+			Ref{Type: "tags", ID: "1"},
+			users["2"],
+			Ref{Type: "tags", ID: "3", Meta: map[string]interface{}{"primary": true}},
+		}, WithoutJSONAPIObject())
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"data": [
+			{"type": "tags", "id": "1"},
+			{"type": "users", "id": "2"},
+			{"type": "tags", "id": "3", "meta": {"primary": true}}
+		]}`, string(data))
+
+		refs, err := UnmarshalIdentifiers(data)
+		require.NoError(t, err)
+		assert.Len(t, refs, 3)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		data, err := MarshalIdentifiers(nil, WithoutJSONAPIObject())
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"data": []}`, string(data))
+	})
+
+	t.Run("top-level members", func(t *testing.T) {
+		data, err := MarshalIdentifiers([]ResourceIdentifier{Ref{Type: "tags", ID: "1"}},
+			WithTopMeta("total", 1), WithoutJSONAPIObject())
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"data": [{"type": "tags", "id": "1"}], "meta": {"total": 1}}`, string(data))
+	})
+}
+
+type address struct {
+	Street string `json:"street,omitempty"`
+	City   string `json:"city,omitempty"`
+}
+
+// addressedUser has nested struct attributes with different omission tags.
+type addressedUser struct {
+	ID       string   `json:"-"`
+	Home     address  `json:"home,omitzero"`
+	Work     *address `json:"work,omitempty"`
+	Shipping address  `json:"shipping,omitempty"`
+}
+
+func (u addressedUser) ResourceID() string   { return u.ID }
+func (u addressedUser) ResourceType() string { return "users" }
+
+func TestMarshal_NestedStructAttributes(t *testing.T) {
+	t.Run("empty nested structs", func(t *testing.T) {
+		data, err := Marshal(addressedUser{ID: "1"}, WithoutJSONAPIObject())
+		require.NoError(t, err)
+
+		// omitzero and nil pointers omit the attribute; omitempty keeps an empty object.
+		attributes := primaryAttributes(t, data)
+		assert.NotContains(t, attributes, "home")
+		assert.NotContains(t, attributes, "work")
+		assert.Equal(t, map[string]interface{}{}, attributes["shipping"])
+	})
+
+	t.Run("populated nested structs", func(t *testing.T) {
+		user := addressedUser{ID: "1", Home: address{City: "Paris"}, Work: &address{Street: "Main St"}}
+		data, err := Marshal(user, WithoutJSONAPIObject())
+		require.NoError(t, err)
+
+		attributes := primaryAttributes(t, data)
+		assert.Equal(t, map[string]interface{}{"city": "Paris"}, attributes["home"])
+		assert.Equal(t, map[string]interface{}{"street": "Main St"}, attributes["work"])
+	})
+}
+
+// primaryAttributes decodes the attributes of the primary resource of a marshaled document.
+func primaryAttributes(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+	var doc Document
+	require.NoError(t, json.Unmarshal(data, &doc))
+	var attrs map[string]interface{}
+	require.NoError(t, json.Unmarshal(doc.Data.one.Attributes, &attrs))
+	return attrs
+}
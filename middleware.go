@@ -1,6 +1,16 @@
 package jsonapi
 
-import "net/http"
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
 
 // Middleware defines the interface for HTTP middleware components that can wrap handlers
 // to provide cross-cutting functionality such as authentication, logging, or request processing.
@@ -44,3 +54,192 @@ func UseRequestResolver(resolver RequestResolver) Middleware {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// UseVaryAccept creates HTTP [Middleware] that adds "Accept" to the Vary response header.
+// Because JSON:API content negotiation depends on the request's Accept header, caches
+// must key responses on it. Existing Vary values set by other handlers are preserved.
+func UseVaryAccept() Middleware {
+	return MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		w.Header().Add("Vary", "Accept")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UseNoBody creates HTTP [Middleware] that rejects GET and DELETE requests carrying
+// a non-empty body with a 400 Bad Request JSON:API error. Such requests are never expected
+// to send a document, and a body usually indicates a misbehaving client.
+func UseNoBody() Middleware {
+	return MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		if (r.Method == http.MethodGet || r.Method == http.MethodDelete) && hasBody(r) {
+			writeErrors(w, http.StatusBadRequest, &Error{
+				Status: strconv.Itoa(http.StatusBadRequest),
+				Title:  http.StatusText(http.StatusBadRequest),
+				Detail: r.Method + " requests must not include a body",
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hasBody reports whether the request carries a non-empty body. Bodies of unknown length
+// are peeked without consuming them, so downstream handlers still see the full body.
+func hasBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return false
+	}
+	if r.ContentLength > 0 {
+		return true
+	}
+
+	reader := bufio.NewReader(r.Body)
+	_, err := reader.Peek(1)
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{reader, r.Body}
+	return err == nil
+}
+
+// UseContentTypeValidation creates HTTP [Middleware] that enforces JSON:API content negotiation.
+// Requests with a body are rejected with 415 Unsupported Media Type unless their Content-Type
+// is application/vnd.api+json, optionally with the ext and profile parameters permitted by the
+// specification. Requests whose Accept header lists the JSON:API media type only with other
+// parameters are rejected with 406 Not Acceptable, even when the header also lists a
+// wildcard. Requests without a body, whatever their method, skip the Content-Type check.
+//
+// Example:
+//
+//	handler := jsonapi.Use(mux, jsonapi.UseContentTypeValidation(), jsonapi.UseNoBody())
+func UseContentTypeValidation() Middleware {
+	return MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		if hasBody(r) && !isJSONAPIContentType(r.Header.Get("Content-Type")) {
+			err := UnsupportedMediaType("Content-Type must be " + jsonapiContentType)
+			writeErrors(w, http.StatusUnsupportedMediaType, &err)
+			return
+		}
+		if acceptsOnlyModifiedJSONAPI(r) {
+			writeErrors(w, http.StatusNotAcceptable, &Error{
+				Status: strconv.Itoa(http.StatusNotAcceptable),
+				Title:  http.StatusText(http.StatusNotAcceptable),
+				Detail: "Accept must include " + jsonapiContentType + " without unsupported parameters",
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isJSONAPIContentType reports whether the Content-Type header value is the JSON:API
+// media type with no parameters other than those permitted by the specification.
+func isJSONAPIContentType(value string) bool {
+	mediaType, params, err := mime.ParseMediaType(value)
+	if err != nil || mediaType != jsonapiContentType {
+		return false
+	}
+	_, quality := params["q"]
+	return !quality && acceptableMediaParams(params)
+}
+
+// acceptsOnlyModifiedJSONAPI reports whether the Accept header lists the JSON:API media
+// type and every instance of it carries parameters other than ext and profile. Other media
+// ranges, including wildcards, are not considered.
+func acceptsOnlyModifiedJSONAPI(r *http.Request) bool {
+	listed := false
+	for _, value := range r.Header.Values("Accept") {
+		for _, accept := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+			if err != nil || mediaType != jsonapiContentType {
+				continue
+			}
+			listed = true
+			if acceptableMediaParams(params) {
+				return false
+			}
+		}
+	}
+	return listed
+}
+
+// UseQueryValidation creates HTTP [Middleware] that rejects requests whose include or
+// fields[type] query parameters reference relationships or attributes unknown to the
+// provided resources, responding with 400 Bad Request JSON:API errors. Resources are
+// registered by their resource type, and their attributes and relationships are discovered
+// with [AttributeNames] and [RelationshipNames].
+//
+// Include paths are validated from the requested resource type, which must be resolved
+// into the request context beforehand, for example by [UseRequestResolver]. Nested path
+// segments are validated against the resource type that related maps the preceding
+// relationship to; segments following an unmapped relationship are not checked.
+//
+// Example:
+//
+//	related := jsonapi.RelatedTypes{"articles": {"author": "users", "tags": "tags"}}
+//	handler := jsonapi.Use(mux, jsonapi.UseQueryValidation(related, Article{}, User{}, Tag{}))
+func UseQueryValidation(related RelatedTypes, resources ...ResourceIdentifier) Middleware {
+	types := make(map[string]ResourceIdentifier, len(resources))
+	for _, res := range resources {
+		types[res.ResourceType()] = res
+	}
+
+	return MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		query := r.URL.Query()
+		request := FromContext(r.Context())
+		errs := validateFields(types, parseFields(query))
+		errs = append(errs, validateInclude(types, related, request.ResourceType, splitList(query.Get("include")))...)
+		if len(errs) > 0 {
+			writeErrors(w, http.StatusBadRequest, errs)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validateFields reports the sparse fieldsets that reference unknown types or fields.
+func validateFields(types map[string]ResourceIdentifier, fields map[string][]string) MultiError {
+	var errs MultiError
+	for resourceType, names := range fields {
+		param := fmt.Sprintf("fields[%s]", resourceType)
+		res, ok := types[resourceType]
+		if !ok {
+			errs = append(errs, parameterError(param, fmt.Sprintf("unknown resource type %q", resourceType)))
+			continue
+		}
+
+		known := append(AttributeNames(res), RelationshipNames(res)...)
+		for _, name := range names {
+			if !slices.Contains(known, name) {
+				errs = append(errs, parameterError(param, fmt.Sprintf("unknown field %q for resource type %q", name, resourceType)))
+			}
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Source.Parameter < errs[j].Source.Parameter
+	})
+	return errs
+}
+
+// RelatedTypes maps resource types to the resource types of their relationships, keyed by
+// relationship name, for use with [UseQueryValidation].
+type RelatedTypes map[string]map[string]string
+
+// validateInclude reports the include paths that reference unknown relationships,
+// starting from the primary resource type.
+func validateInclude(types map[string]ResourceIdentifier, related RelatedTypes, resourceType string, paths []string) MultiError {
+	var errs MultiError
+	for _, path := range paths {
+		res, ok := types[resourceType]
+		for _, name := range strings.Split(path, ".") {
+			if !ok {
+				// the related type is unknown, so the rest of the path cannot be checked.
+				break
+			}
+			if !slices.Contains(RelationshipNames(res), name) {
+				errs = append(errs, parameterError("include", fmt.Sprintf("unknown relationship %q in include path %q", name, path)))
+				break
+			}
+			res, ok = types[related[res.ResourceType()][name]]
+		}
+	}
+	return errs
+}
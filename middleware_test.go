@@ -1,8 +1,11 @@
 package jsonapi
 
 import (
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -155,3 +158,199 @@ func TestMiddleware_Interface(t *testing.T) {
 	assert.True(t, middleware.called)
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+func TestUseVaryAccept(t *testing.T) {
+	t.Run("sets vary header", func(t *testing.T) {
+		handler := Use(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := FromContext(r.Context())
+			ctx.Marshal(w, http.StatusOK, nil)
+		}), UseVaryAccept())
+
+		req := httptest.NewRequest("GET", "/articles", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, "Accept", w.Header().Get("Vary"))
+		assert.Equal(t, "application/vnd.api+json", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("preserves existing vary values", func(t *testing.T) {
+		preset := MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			w.Header().Set("Vary", "Origin")
+			next.ServeHTTP(w, r)
+		})
+		handler := Use(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}), preset, UseVaryAccept())
+
+		req := httptest.NewRequest("GET", "/articles", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, []string{"Origin", "Accept"}, w.Header().Values("Vary"))
+	})
+}
+
+func TestUseContentTypeValidation(t *testing.T) {
+	handler := Use(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), UseContentTypeValidation(), UseNoBody())
+
+	tests := []struct {
+		name        string
+		method      string
+		body        string
+		contentType string
+		accept      string
+		want        int
+	}{
+		{name: "valid content type", method: "POST", body: "{}", contentType: "application/vnd.api+json", want: http.StatusOK},
+		{name: "ext parameter", method: "POST", body: "{}", contentType: `application/vnd.api+json; ext="https://example.com/ext"`, want: http.StatusOK},
+		{name: "unsupported parameter", method: "PATCH", body: "{}", contentType: "application/vnd.api+json; charset=utf-8", want: http.StatusUnsupportedMediaType},
+		{name: "plain json", method: "POST", body: "{}", contentType: "application/json", want: http.StatusUnsupportedMediaType},
+		{name: "missing content type", method: "POST", body: "{}", want: http.StatusUnsupportedMediaType},
+		{name: "bodyless GET", method: "GET", want: http.StatusOK},
+		{name: "bodyless DELETE", method: "DELETE", contentType: "text/plain", want: http.StatusOK},
+		{name: "DELETE with body", method: "DELETE", body: `{"data":[]}`, contentType: "text/plain", want: http.StatusUnsupportedMediaType},
+		{name: "accept with unsupported parameter", method: "GET", accept: "application/vnd.api+json; charset=utf-8", want: http.StatusNotAcceptable},
+		{name: "accept with one acceptable instance", method: "GET", accept: "application/vnd.api+json; charset=utf-8, application/vnd.api+json", want: http.StatusOK},
+		{name: "accept other media type", method: "GET", accept: "text/html", want: http.StatusOK},
+		{name: "accept wildcard with modified instance", method: "GET", accept: "*/*, application/vnd.api+json; charset=utf-8", want: http.StatusNotAcceptable},
+		{name: "accept wildcard", method: "GET", accept: "*/*", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, "/articles", body)
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestUseNoBody(t *testing.T) {
+	handler := Use(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}), UseNoBody())
+
+	t.Run("rejects GET with body", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/articles", strings.NewReader(`{"data":null}`))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "GET requests must not include a body")
+	})
+
+	t.Run("rejects DELETE with body of unknown length", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/articles/1", strings.NewReader(`{}`))
+		req.ContentLength = -1
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("allows GET without body", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/articles", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("allows empty body of unknown length", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/articles", strings.NewReader(""))
+		req.ContentLength = -1
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("allows POST with body", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/articles", strings.NewReader(`{"data":null}`))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"data":null}`, w.Body.String())
+	})
+}
+
+func TestUseQueryValidation(t *testing.T) {
+	handler := Use(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), UseRequestResolver(RequestResolverFunc(func(r *http.Request) *Context {
+		return &Context{ResourceType: "articles"}
+	})), UseQueryValidation(RelatedTypes{
+		"articles": {"author": "users", "tags": "tags"},
+	}, Article{}, User{}, Tag{}))
+
+	serve := func(target string) (int, Document) {
+		req := httptest.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		var doc Document
+		if w.Code != http.StatusOK {
+			json.Unmarshal(w.Body.Bytes(), &doc)
+		}
+		return w.Code, doc
+	}
+
+	t.Run("valid parameters", func(t *testing.T) {
+		code, _ := serve("/articles?include=author,tags&fields[articles]=title,author&fields[users]=name")
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("unknown include relationship", func(t *testing.T) {
+		code, doc := serve("/articles?include=author,comments")
+		assert.Equal(t, http.StatusBadRequest, code)
+		if assert.Len(t, doc.Errors, 1) {
+			assert.Equal(t, "include", doc.Errors[0].Source.Parameter)
+			assert.Equal(t, `unknown relationship "comments" in include path "comments"`, doc.Errors[0].Detail)
+		}
+	})
+
+	t.Run("unknown nested include relationship", func(t *testing.T) {
+		code, doc := serve("/articles?include=author.posts")
+		assert.Equal(t, http.StatusBadRequest, code)
+		if assert.Len(t, doc.Errors, 1) {
+			assert.Equal(t, `unknown relationship "posts" in include path "author.posts"`, doc.Errors[0].Detail)
+		}
+	})
+
+	t.Run("unknown nested include of to-many relationship", func(t *testing.T) {
+		code, doc := serve("/articles?include=tags.owner")
+		assert.Equal(t, http.StatusBadRequest, code)
+		if assert.Len(t, doc.Errors, 1) {
+			assert.Equal(t, `unknown relationship "owner" in include path "tags.owner"`, doc.Errors[0].Detail)
+		}
+	})
+
+	t.Run("unknown fields", func(t *testing.T) {
+		code, doc := serve("/articles?fields[articles]=title,summary&fields[videos]=url")
+		assert.Equal(t, http.StatusBadRequest, code)
+		if assert.Len(t, doc.Errors, 2) {
+			assert.Equal(t, "fields[articles]", doc.Errors[0].Source.Parameter)
+			assert.Equal(t, `unknown field "summary" for resource type "articles"`, doc.Errors[0].Detail)
+			assert.Equal(t, "fields[videos]", doc.Errors[1].Source.Parameter)
+			assert.Equal(t, `unknown resource type "videos"`, doc.Errors[1].Detail)
+		}
+	})
+}
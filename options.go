@@ -1,6 +1,7 @@
 package jsonapi
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -20,13 +21,45 @@ type Options interface {
 
 // options holds the internal configuration state for marshaling and unmarshaling operations.
 type options struct {
-	topLinks        map[string]Link         // Top-level document links
-	topMeta         map[string]interface{}  // Top-level document metadata
-	errors          []*Error                // List of document errors
-	includes        map[string]*Resource    // Map of included resources by UID
-	maxIncludeDepth int                     // Maximum depth for including related resources
-	validateType    bool                    // Whether to validate resource types during unmarshaling
-	linkResolver    map[string]LinkResolver // Map of link resolvers by key name for generating URLs
+	topLinks        map[string]Link            // Top-level document links
+	topMeta         map[string]interface{}     // Top-level document metadata
+	errors          []*Error                   // List of document errors
+	errorStatus     map[*Error]int             // HTTP status of errors converted from plain Go errors
+	errorTitle      func(status int) string    // Maps HTTP status codes to titles of converted errors
+	errorLess       func(a, b Error) bool      // Orders the errors of the document
+	includes        map[string]*Resource       // Map of included resources by UID
+	includeOrder    []string                   // Included resource UIDs in traversal order
+	maxIncluded     int                        // Maximum number of included resources, or zero for no limit
+	maxIncludedErr  bool                       // Whether exceeding maxIncluded is an error rather than truncation
+	maxIncludeDepth int                        // Maximum depth for including related resources
+	validateType    bool                       // Whether to validate resource types during unmarshaling
+	foldTypes       bool                       // Whether resource type validation ignores case
+	onTypeMismatch  func(expected, got string) // Invoked for resource type mismatches when not validating types
+	strictIDs       bool                       // Whether resources declaring both id and lid are rejected
+	requireType     bool                       // Whether marshaling resources with an empty type is an error
+	singleFromArray bool                       // Whether a one-element data array may fill a single resource target
+	collectErrors   bool                       // Whether attribute decoding errors are collected into a MultiError
+	typeRegistry    TypeRegistry               // Concrete types of resources unmarshaled into interface targets
+	linkResolver    map[string]LinkResolver    // Map of link resolvers by key name for generating URLs
+	version         string                     // JSON:API version emitted in the top-level jsonapi member
+	inspector       func(*Document)            // Callback invoked with the final document before encoding
+	sparseFields    map[string][]string        // Sparse fieldsets to apply by resource type (from WithRequest)
+	omitLinkage     bool                       // Whether relationships omit resource linkage data
+	linksOnly       bool                       // Whether relationships with links omit linkage unless included
+	paginator       Paginator                  // Generates top-level pagination links from the primary data
+	omitEmptyRefs   bool                       // Whether relationships without data, links, or meta are omitted
+	emptyRefsObject bool                       // Whether resources always emit a relationships object
+	countData       bool                       // Whether collections report their size in the top-level meta.count
+	timeFormat      string                     // Layout of time attributes, or empty for the default encoding
+	includePaths    map[string]bool            // Relationship paths to include, or nil to include all (from WithRequest)
+	includedMeta    IncludedMetaFunc           // Annotates included resources with additional metadata
+	includedSort    map[string][]string        // Attributes to sort included resources by, per resource type
+	relationshipMap func(name string) string   // Transforms relationship names into document keys
+	marshal         MarshalFunc                // Encodes the final document, or nil for the package JSON marshaler
+	validationCtx   context.Context            // Context passed to ResourceValidator implementations
+	allowedAttrs    map[string]map[string]bool // Attribute names accepted per resource type, or nil to accept all
+	strictAttrs     bool                       // Whether disallowed attributes are rejected rather than ignored
+	stream          bool                       // Whether HTTP responses are encoded directly to the writer
 
 	// Query parameter fields used by the client layer for building request URLs.
 	queryInclude    []string            // include=author,tags
@@ -48,12 +81,44 @@ type options struct {
 func fromOptionsOverride(base *options) Options {
 	return optionsFunc(func(options *options) {
 		options.includes = base.includes
+		options.includeOrder = base.includeOrder
+		options.maxIncluded = base.maxIncluded
+		options.maxIncludedErr = base.maxIncludedErr
 		options.maxIncludeDepth = base.maxIncludeDepth
+		options.includePaths = base.includePaths
+		options.includedMeta = base.includedMeta
+		options.includedSort = base.includedSort
+		options.relationshipMap = base.relationshipMap
 		options.topLinks = base.topLinks
 		options.topMeta = base.topMeta
 		options.validateType = base.validateType
+		options.foldTypes = base.foldTypes
+		options.onTypeMismatch = base.onTypeMismatch
+		options.strictIDs = base.strictIDs
+		options.requireType = base.requireType
+		options.singleFromArray = base.singleFromArray
+		options.collectErrors = base.collectErrors
+		options.typeRegistry = base.typeRegistry
 		options.errors = base.errors
+		options.errorStatus = base.errorStatus
+		options.errorTitle = base.errorTitle
+		options.errorLess = base.errorLess
 		options.linkResolver = base.linkResolver
+		options.version = base.version
+		options.inspector = base.inspector
+		options.sparseFields = base.sparseFields
+		options.omitLinkage = base.omitLinkage
+		options.linksOnly = base.linksOnly
+		options.paginator = base.paginator
+		options.omitEmptyRefs = base.omitEmptyRefs
+		options.emptyRefsObject = base.emptyRefsObject
+		options.countData = base.countData
+		options.timeFormat = base.timeFormat
+		options.marshal = base.marshal
+		options.validationCtx = base.validationCtx
+		options.allowedAttrs = base.allowedAttrs
+		options.strictAttrs = base.strictAttrs
+		options.stream = base.stream
 	})
 }
 
@@ -63,6 +128,7 @@ func applyOptions(opts []Options) options {
 		topLinks:        make(map[string]Link),
 		topMeta:         make(map[string]interface{}),
 		includes:        make(map[string]*Resource),
+		errorStatus:     make(map[*Error]int),
 		linkResolver:    make(map[string]LinkResolver),
 		queryFields:     make(map[string][]string),
 		queryPageParams: make(map[string]string),
@@ -70,6 +136,8 @@ func applyOptions(opts []Options) options {
 		queryParams:     make(map[string][]string),
 		maxIncludeDepth: math.MaxInt,
 		validateType:    false,
+		version:         Version,
+		validationCtx:   context.Background(),
 	}
 
 	for _, opt := range opts {
@@ -125,6 +193,83 @@ func WithMaxIncludeDepth(depth int) Options {
 	})
 }
 
+// WithMaxIncluded caps the number of resources in the included array of the document.
+// Resources beyond the cap are dropped, and the top-level meta object records the
+// truncation under the "included" key with the "total" number of related resources
+// found and the "limit" applied:
+//
+//	"meta": {"included": {"total": 1200, "limit": 100}}
+//
+// A value of zero or less disables the cap. See [WithStrictMaxIncluded] to fail
+// marshaling instead of truncating.
+func WithMaxIncluded(n int) Options {
+	return optionsFunc(func(opts *options) {
+		opts.maxIncluded = n
+		opts.maxIncludedErr = false
+	})
+}
+
+// WithStrictMaxIncluded caps the number of resources in the included array of the document,
+// returning an error from marshaling when the cap is exceeded.
+func WithStrictMaxIncluded(n int) Options {
+	return optionsFunc(func(opts *options) {
+		opts.maxIncluded = n
+		opts.maxIncludedErr = true
+	})
+}
+
+// WithRelationshipLinkage controls whether relationship objects emit resource linkage
+// in their data member. Linkage is enabled by default. When disabled, relationships
+// only carry their links and meta, and related resources are not included in the
+// document. Relationships without links or meta keep their linkage, and [MarshalRef]
+// always emits it. This is useful for collection endpoints that want relationship links
+// without potentially large data arrays.
+func WithRelationshipLinkage(enabled bool) Options {
+	return optionsFunc(func(opts *options) {
+		opts.omitLinkage = !enabled
+	})
+}
+
+// WithRelationshipsAsLinks omits the resource linkage of relationships that carry links
+// but whose related resources are not included in the document, such as relationships
+// left out of the request's include parameter (see [WithRequest]). Clients follow the
+// relationship links instead. Included relationships, and relationships without links,
+// keep their linkage.
+func WithRelationshipsAsLinks() Options {
+	return optionsFunc(func(opts *options) {
+		opts.linksOnly = true
+	})
+}
+
+// WithOmitEmptyRelationships omits relationship objects that carry no links, no meta,
+// and null resource linkage, such as an optional to-one relationship that is not set.
+// Without this option, such relationships are emitted as {"data": null}.
+// Empty to-many relationships are always emitted, as their empty linkage is meaningful.
+func WithOmitEmptyRelationships() Options {
+	return optionsFunc(func(opts *options) {
+		opts.omitEmptyRefs = true
+	})
+}
+
+// WithEmptyRelationshipsObject makes every marshaled resource emit a relationships object,
+// even when it has no relationships, for clients that expect the member to be present.
+// Resources without relationships then marshal with "relationships":{}.
+func WithEmptyRelationshipsObject() Options {
+	return optionsFunc(func(opts *options) {
+		opts.emptyRefsObject = true
+	})
+}
+
+// WithCollectionCount sets the top-level meta.count member of collection documents to the
+// number of resources in the primary data. Unlike [Context.MarshalList], which reports the
+// total across all pages, the count only covers the resources in the document itself.
+// Documents with a single resource as primary data are unaffected.
+func WithCollectionCount() Options {
+	return optionsFunc(func(opts *options) {
+		opts.countData = true
+	})
+}
+
 // WithTypeValidation enables resource type validation during unmarshaling operations.
 // When enabled, the unmarshaler will verify that the resource type in the document
 // matches the expected type of the target struct.
@@ -134,12 +279,167 @@ func WithTypeValidation() Options {
 	})
 }
 
+// DocumentJSONAPIVersion sets the version reported in the top-level jsonapi member
+// of marshaled documents. By default, documents report [Version].
+func DocumentJSONAPIVersion(version string) Options {
+	return optionsFunc(func(opts *options) {
+		opts.version = version
+	})
+}
+
+// WithMarshaler sets the function used to encode the final document, overriding the
+// package-level marshaler configured with [SetJSONMarshaler] for a single call.
+// See [ContextWithMarshaler] to select a marshaler per request.
+func WithMarshaler(fn MarshalFunc) Options {
+	return optionsFunc(func(opts *options) {
+		opts.marshal = fn
+	})
+}
+
+// WithStreaming makes [Context.Marshal] and related methods write the document to the
+// response with [MarshalWrite] instead of marshaling it with [Marshal] first. The document
+// is still encoded in memory before it is written. Because the status code is written
+// before encoding starts, encoding errors can no longer be reported with a different status.
+func WithStreaming() Options {
+	return optionsFunc(func(opts *options) {
+		opts.stream = true
+	})
+}
+
+// WithoutJSONAPIObject disables emission of the top-level jsonapi member
+// on marshaled documents.
+func WithoutJSONAPIObject() Options {
+	return optionsFunc(func(opts *options) {
+		opts.version = ""
+	})
+}
+
+// WithDocumentInspector registers a callback that receives the final [Document]
+// after all options have been applied and immediately before it is encoded to JSON.
+// This is useful for logging or debugging the intermediate document structure.
+//
+// Example:
+//
+//	Marshal(article, WithDocumentInspector(func(doc *Document) {
+//		log.Printf("included resources: %d", len(doc.Included))
+//	}))
+func WithDocumentInspector(fn func(*Document)) Options {
+	return optionsFunc(func(opts *options) {
+		opts.inspector = fn
+	})
+}
+
+// WithCaseInsensitiveTypes makes resource type validation during unmarshaling ignore case,
+// so a document with type "Users" is accepted by a target whose type is "users".
+// It only has an effect when combined with [WithTypeValidation].
+func WithCaseInsensitiveTypes() Options {
+	return optionsFunc(func(opts *options) {
+		opts.foldTypes = true
+	})
+}
+
+// WithTypeMismatchHandler registers a callback invoked when an unmarshaled resource's type
+// differs from the type of its target, allowing applications to log or count mismatches
+// without failing. It is only invoked when [WithTypeValidation] is not set, since type
+// validation turns mismatches into errors.
+func WithTypeMismatchHandler(fn func(expected, got string)) Options {
+	return optionsFunc(func(opts *options) {
+		opts.onTypeMismatch = fn
+	})
+}
+
+// WithStrictIdentifiers enables strict validation of resource identification during
+// unmarshaling, rejecting resources that declare both an id and a lid.
+func WithStrictIdentifiers() Options {
+	return optionsFunc(func(opts *options) {
+		opts.strictIDs = true
+	})
+}
+
+// WithRequireType makes marshaling fail for resources whose ResourceType returns an empty
+// string, including related resources and the resource identifiers of relationship linkage,
+// instead of emitting objects without a type. An empty type is usually a bug, such as a
+// ResourceType method reading an unset field.
+func WithRequireType() Options {
+	return optionsFunc(func(opts *options) {
+		opts.requireType = true
+	})
+}
+
+// WithSingleFromArray allows unmarshaling a document whose primary data is an array holding
+// exactly one resource into a single resource target, for producers that wrap single
+// resources in an array. Without it, and for arrays of any other length, unmarshaling an
+// array into a non-slice target is an error.
+func WithSingleFromArray() Options {
+	return optionsFunc(func(opts *options) {
+		opts.singleFromArray = true
+	})
+}
+
+// WithCollectErrors makes unmarshaling report every attribute that fails to decode rather
+// than only the first. The failures are returned as a [MultiError] of 422 Unprocessable
+// Entity errors whose source pointers reference the offending attributes, suitable for
+// passing directly to [Context.MarshalErrors].
+//
+// Example:
+//
+//	if err := ctx.Unmarshal(r.Body, &article, jsonapi.WithCollectErrors()); err != nil {
+//		ctx.MarshalErrors(w, http.StatusUnprocessableEntity, err)
+//		return
+//	}
+func WithCollectErrors() Options {
+	return optionsFunc(func(opts *options) {
+		opts.collectErrors = true
+	})
+}
+
+// WithAllowedAttributes restricts the attributes accepted when unmarshaling resources of
+// the given type, protecting against mass assignment. Attributes outside the list are
+// ignored, or rejected with a 403 Forbidden [Error] when [WithStrictAttributes] is set.
+// Resource types without an allow list accept all attributes. The list is selected by the
+// type of the unmarshal target, not the type sent by the client.
+//
+// Example:
+//
+//	err := ctx.Unmarshal(r.Body, &user,
+//		jsonapi.WithAllowedAttributes("users", "name", "email"),
+//		jsonapi.WithStrictAttributes(),
+//	)
+func WithAllowedAttributes(resourceType string, names ...string) Options {
+	return optionsFunc(func(opts *options) {
+		if opts.allowedAttrs == nil {
+			opts.allowedAttrs = make(map[string]map[string]bool)
+		}
+		if opts.allowedAttrs[resourceType] == nil {
+			opts.allowedAttrs[resourceType] = make(map[string]bool)
+		}
+		for _, name := range names {
+			opts.allowedAttrs[resourceType][name] = true
+		}
+	})
+}
+
+// WithStrictAttributes rejects attributes outside the lists configured with
+// [WithAllowedAttributes] instead of silently ignoring them.
+func WithStrictAttributes() Options {
+	return optionsFunc(func(opts *options) {
+		opts.strictAttrs = true
+	})
+}
+
 // WithError adds an error to the JSON:API document's error list.
 // If the provided error is not already a JSON:API [Error] type, it will be converted
 // to one using the provided HTTP status code. The error's title will be set to the
 // standard HTTP status text, and the detail will be set to the error's message.
+// A [MultiError] adds each of its errors to the list.
 func WithError(status int, err error) Options {
 	return optionsFunc(func(opts *options) {
+		var multiErr MultiError
+		if errors.As(err, &multiErr) {
+			opts.errors = append(opts.errors, multiErr...)
+			return
+		}
+
 		var jsonErr *Error
 		if !errors.As(err, &jsonErr) {
 			jsonErr = &Error{
@@ -147,11 +447,138 @@ func WithError(status int, err error) Options {
 				Title:  http.StatusText(status),
 				Detail: err.Error(),
 			}
+			opts.errorStatus[jsonErr] = status
 		}
 		opts.errors = append(opts.errors, jsonErr)
 	})
 }
 
+// IncludedMetaFunc returns metadata to merge into the meta object of a related resource
+// when it is added to the included array. The path is the dot-separated relationship path
+// through which the resource was first reached, such as "author" or "comments.author".
+type IncludedMetaFunc func(path string, id ResourceIdentifier) map[string]interface{}
+
+// SortErrorsBy sorts the errors of the document using the provided less function
+// before it is written. The sort is stable, so errors comparing equal keep the order in
+// which they were added. In handlers, add it to [Context.ErrorOptions], or pass it to
+// [Context.MarshalErrorsWith], to sort the errors of the documents written through the
+// context.
+//
+// Example:
+//
+//	// most severe status first
+//	jsonapi.SortErrorsBy(func(a, b jsonapi.Error) bool { return a.Status > b.Status })
+func SortErrorsBy(less func(a, b Error) bool) Options {
+	return optionsFunc(func(opts *options) {
+		opts.errorLess = less
+	})
+}
+
+// WithIncludedMeta annotates included resources with metadata returned by fn, such as the
+// reason a resource was included. The returned values are merged over any metadata the
+// resource provides through [MetaMarshaler]. Returning nil leaves the resource unchanged.
+//
+// Example:
+//
+//	jsonapi.Marshal(article, jsonapi.WithIncludedMeta(func(path string, id jsonapi.ResourceIdentifier) map[string]interface{} {
+//		return map[string]interface{}{"includedVia": path}
+//	}))
+func WithIncludedMeta(fn IncludedMetaFunc) Options {
+	return optionsFunc(func(opts *options) {
+		opts.includedMeta = fn
+	})
+}
+
+// WithIncludedSort orders the included resources of the given type by the provided
+// attributes. Prefix an attribute with "-" to sort in descending order. Resources of
+// the type keep their positions relative to included resources of other types, and
+// resources with equal attribute values keep their traversal order.
+//
+// Example:
+//
+//	jsonapi.Marshal(article, jsonapi.WithIncludedSort("comments", "-created_at"))
+func WithIncludedSort(resourceType string, fields ...string) Options {
+	return optionsFunc(func(opts *options) {
+		if opts.includedSort == nil {
+			opts.includedSort = make(map[string][]string)
+		}
+		opts.includedSort[resourceType] = fields
+	})
+}
+
+// WithRelationshipNameMapper transforms relationship names into the keys used in the
+// relationships object of marshaled resources, for example to emit kebab-case keys.
+// Sparse fieldsets and include paths refer to the transformed keys.
+//
+// When unmarshaling, the mapping is reversed for targets that also implement
+// [RelationshipMarshaler], by applying fn to each name returned by Relationships.
+// Document keys that do not correspond to a known relationship are passed through as is.
+//
+// Example:
+//
+//	// "blogPost" is emitted as "blog-post"
+//	jsonapi.Marshal(comment, jsonapi.WithRelationshipNameMapper(toKebabCase))
+func WithRelationshipNameMapper(fn func(name string) string) Options {
+	return optionsFunc(func(opts *options) {
+		opts.relationshipMap = fn
+	})
+}
+
+// encode marshals the document using the configured marshaler, falling back
+// to the package-level JSON marshaler.
+func (o *options) encode(v interface{}) ([]byte, error) {
+	if o.marshal != nil {
+		return o.marshal(v)
+	}
+	return jsonMarshal(v)
+}
+
+// relationshipKey returns the document key of the named relationship.
+func (o *options) relationshipKey(name string) string {
+	if o.relationshipMap == nil {
+		return name
+	}
+	return o.relationshipMap(name)
+}
+
+// relationshipNames returns the relationship names of the target keyed by their document
+// keys, reversing [WithRelationshipNameMapper]. It returns nil if no mapper is configured
+// or the target does not declare its relationships.
+func (o *options) relationshipNames(target interface{}) map[string]string {
+	marshaler, ok := target.(RelationshipMarshaler)
+	if o.relationshipMap == nil || !ok {
+		return nil
+	}
+	names := make(map[string]string)
+	for name := range marshaler.Relationships() {
+		names[o.relationshipMap(name)] = name
+	}
+	return names
+}
+
+// WithErrorTitleMapper overrides the titles of errors that [WithError] converts from plain
+// Go errors, which otherwise default to the standard HTTP status text. Errors that are
+// already JSON:API [Error] values keep their own titles. The option may be applied before
+// or after the errors it affects. In handlers, add it to [Context.ErrorOptions] to apply it
+// to every error document written through the context.
+//
+// Example:
+//
+//	jsonapi.Marshal(nil,
+//		jsonapi.WithErrorTitleMapper(func(status int) string {
+//			if status == http.StatusNotFound {
+//				return "No Such Resource"
+//			}
+//			return http.StatusText(status)
+//		}),
+//		jsonapi.WithError(http.StatusNotFound, err),
+//	)
+func WithErrorTitleMapper(fn func(status int) string) Options {
+	return optionsFunc(func(opts *options) {
+		opts.errorTitle = fn
+	})
+}
+
 // LinkResolver defines the interface for resolving resource and relationship links
 // during marshaling operations. This allows the marshaler to generate URLs without
 // requiring resources to have server awareness.
@@ -191,7 +618,8 @@ type LinkResolver interface {
 
 	// ResolveRelationshipLink resolves a link for a relationship object.
 	// The key parameter specifies the link name (e.g., "self", "related").
-	// The name parameter is the relationship name (e.g., "author", "tags").
+	// The name parameter is the relationship name as it appears in the document (e.g., "author",
+	// "tags"), after any [WithRelationshipNameMapper] mapping.
 	// The id parameter provides access to the parent resource's context.
 	// Returns the Link and true if the key is recognized, or zero Link and false if not.
 	ResolveRelationshipLink(key string, name string, id RelationshipMarshaler) (Link, bool)
@@ -307,6 +735,22 @@ func WithInclude(relationships ...string) Options {
 	})
 }
 
+// WithEmptyInclude sends an empty `include` query parameter when no relationships
+// are requested with [WithInclude], which asks the server not to include any
+// related resources, not even its defaults.
+//
+// Example:
+//
+//	client.Fetch(ctx, "articles", "1", jsonapi.WithEmptyInclude())
+//	// produces: ?include=
+func WithEmptyInclude() Options {
+	return optionsFunc(func(opts *options) {
+		if opts.queryInclude == nil {
+			opts.queryInclude = []string{}
+		}
+	})
+}
+
 // WithFields specifies sparse fieldsets for a resource type.
 // The fields are joined as a comma-separated list in the `fields[{type}]` query parameter.
 //
@@ -432,7 +876,7 @@ func (o *options) buildQueryParams() url.Values {
 	params := url.Values{}
 
 	// Encode include as comma-separated list.
-	if len(o.queryInclude) > 0 {
+	if o.queryInclude != nil {
 		params.Set("include", strings.Join(o.queryInclude, ","))
 	}
 
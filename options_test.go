@@ -2,16 +2,21 @@ package jsonapi
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	mathrand "math/rand"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
 	"testing/quick"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type testResourceWithRel struct {
@@ -40,6 +45,41 @@ func TestWithTopMeta(t *testing.T) {
 	assert.Equal(t, float64(42), doc.Meta["total"])
 }
 
+func TestWithTopMeta_Merging(t *testing.T) {
+	t.Run("options accumulate keys", func(t *testing.T) {
+		data, err := Marshal(testResource{ID: "1"},
+			WithTopMeta("page", map[string]interface{}{"total": 3}), // e.g. from pagination middleware
+			WithTopMeta("elapsedMs", 12),                            // e.g. from timing middleware
+			WithTopMeta("elapsedMs", 15),
+		)
+		require.NoError(t, err)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(data, &doc))
+		assert.Equal(t, map[string]interface{}{
+			"page":      map[string]interface{}{"total": float64(3)},
+			"elapsedMs": float64(15),
+		}, doc.Meta)
+	})
+
+	t.Run("merges into document members", func(t *testing.T) {
+		original := map[string]interface{}{"source": "cache", "elapsedMs": 1}
+		in := &Document{
+			Meta:  original,
+			Links: map[string]Link{"self": {Href: "/articles"}},
+		}
+		data, err := Marshal(in, WithTopMeta("elapsedMs", 15), WithTopHref("next", "/articles?page=2"))
+		require.NoError(t, err)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(data, &doc))
+		assert.Equal(t, map[string]interface{}{"source": "cache", "elapsedMs": float64(15)}, doc.Meta)
+		assert.Equal(t, "/articles", doc.Links["self"].Href)
+		assert.Equal(t, "/articles?page=2", doc.Links["next"].Href)
+		assert.Equal(t, 1, original["elapsedMs"], "the caller's meta is not modified")
+	})
+}
+
 func TestWithTopLink(t *testing.T) {
 	resource := testResource{ID: "1", Name: "test"}
 
@@ -73,6 +113,49 @@ func TestWithMaxIncludeDepth(t *testing.T) {
 	assert.NotNil(t, data)
 }
 
+func TestWithMaxIncludeDepth_Truncation(t *testing.T) {
+	post := blogPost{ID: "1", AuthorID: "1", CommentIDs: []string{"1", "2"}}
+
+	marshal := func(t *testing.T, depth int) Document {
+		t.Helper()
+		data, err := Marshal(post, WithMaxIncludeDepth(depth))
+		require.NoError(t, err)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(data, &doc))
+		return doc
+	}
+	includedUIDs := func(doc Document) []string {
+		uids := make([]string, 0, len(doc.Included))
+		for _, res := range doc.Included {
+			uids = append(uids, res.Type+":"+res.ID)
+		}
+		sort.Strings(uids)
+		return uids
+	}
+
+	t.Run("depth zero emits linkage only", func(t *testing.T) {
+		doc := marshal(t, 0)
+		assert.Empty(t, doc.Included)
+		assert.JSONEq(t, `{"type":"users","id":"1"}`, mustJSON(t, doc.Data.one.Relationships["author"].Data))
+	})
+
+	t.Run("depth one includes direct relationships", func(t *testing.T) {
+		doc := marshal(t, 1)
+		assert.Equal(t, []string{"comments:1", "comments:2", "users:1"}, includedUIDs(doc))
+		for _, res := range doc.Included {
+			if res.Type == "comments" {
+				assert.JSONEq(t, `{"type":"users","id":"2"}`, mustJSON(t, res.Relationships["author"].Data))
+			}
+		}
+	})
+
+	t.Run("depth two includes nested relationships", func(t *testing.T) {
+		doc := marshal(t, 2)
+		assert.Equal(t, []string{"comments:1", "comments:2", "users:1", "users:2"}, includedUIDs(doc))
+	})
+}
+
 func TestWithTypeValidation(t *testing.T) {
 	resource := testResource{ID: "1", Name: "test"}
 
@@ -152,6 +235,24 @@ func TestBuildQueryParams_Include(t *testing.T) {
 	assert.Equal(t, "author,tags", params.Get("include"))
 }
 
+func TestBuildQueryParams_EmptyInclude(t *testing.T) {
+	opts := applyOptions(nil)
+	absent := opts.buildQueryParams()
+	assert.NotContains(t, absent, "include")
+
+	opts = applyOptions([]Options{WithInclude()})
+	assert.NotContains(t, opts.buildQueryParams(), "include")
+
+	opts = applyOptions([]Options{WithEmptyInclude()})
+	empty := opts.buildQueryParams()
+	assert.Contains(t, empty, "include")
+	assert.Equal(t, "", empty.Get("include"))
+	assert.Equal(t, "include=", empty.Encode())
+
+	opts = applyOptions([]Options{WithEmptyInclude(), WithInclude("author")})
+	assert.Equal(t, "author", opts.buildQueryParams().Get("include"))
+}
+
 func TestBuildQueryParams_Fields(t *testing.T) {
 	opts := applyOptions([]Options{
 		WithFields("articles", "title", "content"),
@@ -567,3 +668,523 @@ func TestProperty_QueryParametersCorrectlyEncoded(t *testing.T) {
 		t.Errorf("Property 6 failed: %v", err)
 	}
 }
+
+func TestWithDocumentInspector(t *testing.T) {
+	t.Run("inspects final document", func(t *testing.T) {
+		var inspected *Document
+		resource := testResource{ID: "1", Name: "test"}
+
+		_, err := Marshal(resource,
+			WithTopMeta("total", 1),
+			WithDocumentInspector(func(doc *Document) { inspected = doc }),
+		)
+		assert.NoError(t, err)
+		if assert.NotNil(t, inspected) {
+			assert.Equal(t, "1", inspected.Data.one.ID)
+			assert.Equal(t, "test", inspected.Data.one.Type)
+			assert.Equal(t, 1, inspected.Meta["total"])
+		}
+	})
+
+	t.Run("modifications are encoded", func(t *testing.T) {
+		resource := testResource{ID: "1", Name: "test"}
+
+		data, err := Marshal(resource, WithDocumentInspector(func(doc *Document) {
+			doc.Meta = map[string]interface{}{"inspected": true}
+		}))
+		assert.NoError(t, err)
+
+		var doc Document
+		err = json.Unmarshal(data, &doc)
+		assert.NoError(t, err)
+		assert.Equal(t, true, doc.Meta["inspected"])
+	})
+
+	t.Run("inspects relationship documents", func(t *testing.T) {
+		var inspected *Document
+		article := Article{ID: "1", TagIDs: []string{"1", "2"}}
+
+		_, err := MarshalRef(article, "tags", WithDocumentInspector(func(doc *Document) { inspected = doc }))
+		assert.NoError(t, err)
+		if assert.NotNil(t, inspected) {
+			assert.Len(t, inspected.Data.many, 2)
+		}
+	})
+}
+
+func TestWithMaxIncluded(t *testing.T) {
+	article := Article{ID: "1", AuthorID: "1", TagIDs: []string{"1", "2", "3"}}
+
+	t.Run("includes all related resources without a cap", func(t *testing.T) {
+		data, err := Marshal(article)
+		assert.NoError(t, err)
+
+		var doc Document
+		err = json.Unmarshal(data, &doc)
+		assert.NoError(t, err)
+		assert.Len(t, doc.Included, 4)
+		assert.NotContains(t, doc.Meta, "included")
+	})
+
+	t.Run("truncates at the cap", func(t *testing.T) {
+		data, err := Marshal(article, WithMaxIncluded(2))
+		assert.NoError(t, err)
+
+		var doc Document
+		err = json.Unmarshal(data, &doc)
+		assert.NoError(t, err)
+		if assert.Len(t, doc.Included, 2) {
+			assert.Equal(t, "users", doc.Included[0].Type)
+			assert.Equal(t, "tags", doc.Included[1].Type)
+			assert.Equal(t, "1", doc.Included[1].ID)
+		}
+		assert.Equal(t, map[string]interface{}{
+			"total": float64(4),
+			"limit": float64(2),
+		}, doc.Meta["included"])
+	})
+
+	t.Run("no truncation within the cap", func(t *testing.T) {
+		data, err := Marshal(article, WithMaxIncluded(4))
+		assert.NoError(t, err)
+
+		var doc Document
+		err = json.Unmarshal(data, &doc)
+		assert.NoError(t, err)
+		assert.Len(t, doc.Included, 4)
+		assert.NotContains(t, doc.Meta, "included")
+	})
+
+	t.Run("strict cap returns an error", func(t *testing.T) {
+		_, err := Marshal(article, WithStrictMaxIncluded(2))
+		assert.Error(t, err)
+	})
+
+	t.Run("collections share included resources", func(t *testing.T) {
+		list := []Article{
+			{ID: "1", AuthorID: "1"},
+			{ID: "2", AuthorID: "1"},
+		}
+		data, err := Marshal(list, WithMaxIncluded(1))
+		assert.NoError(t, err)
+
+		var doc Document
+		err = json.Unmarshal(data, &doc)
+		assert.NoError(t, err)
+		assert.Len(t, doc.Included, 1)
+		assert.NotContains(t, doc.Meta, "included")
+	})
+}
+
+func TestWithRelationshipLinkage(t *testing.T) {
+	article := Article{ID: "1", AuthorID: "1", TagIDs: []string{"1", "2"}}
+
+	t.Run("linkage on", func(t *testing.T) {
+		data, err := Marshal(article, WithRelationshipLinkage(true), WithDefaultLinks("http://example.com"))
+		assert.NoError(t, err)
+
+		var doc Document
+		assert.NoError(t, json.Unmarshal(data, &doc))
+		tags := doc.Data.one.Relationships["tags"]
+		if assert.NotNil(t, tags) {
+			assert.NotNil(t, tags.Data)
+			assert.Len(t, tags.Data.many, 2)
+			assert.Contains(t, tags.Links, "related")
+		}
+		assert.Len(t, doc.Included, 3)
+	})
+
+	t.Run("linkage off", func(t *testing.T) {
+		data, err := Marshal(article, WithRelationshipLinkage(false), WithDefaultLinks("http://example.com"))
+		assert.NoError(t, err)
+
+		var doc Document
+		assert.NoError(t, json.Unmarshal(data, &doc))
+		for name, rel := range doc.Data.one.Relationships {
+			assert.Nil(t, rel.Data, name)
+			assert.Equal(t, "http://example.com/articles/1/"+name, rel.Links["related"].Href)
+		}
+		assert.Empty(t, doc.Included)
+		assert.NotContains(t, string(data), `"data":[`)
+	})
+
+	t.Run("linkage kept without links or meta", func(t *testing.T) {
+		data, err := Marshal(article, WithRelationshipLinkage(false))
+		assert.NoError(t, err)
+
+		var doc Document
+		assert.NoError(t, json.Unmarshal(data, &doc))
+		author := doc.Data.one.Relationships["author"]
+		if assert.NotNil(t, author) && assert.NotNil(t, author.Data) {
+			assert.Equal(t, "1", author.Data.one.ID)
+		}
+		assert.NotContains(t, string(data), `"author":{}`)
+		assert.Empty(t, doc.Included)
+	})
+
+	t.Run("relationship document always has linkage", func(t *testing.T) {
+		data, err := MarshalRef(article, "tags", WithRelationshipLinkage(false), WithoutJSONAPIObject())
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"data":[{"type":"tags","id":"1"},{"type":"tags","id":"2"}]}`, string(data))
+	})
+}
+
+func TestWithOmitEmptyRelationships(t *testing.T) {
+	article := Article{ID: "1"}
+
+	t.Run("emits null to-one by default", func(t *testing.T) {
+		data, err := Marshal(article)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `"author":{"data":null}`)
+		assert.Contains(t, string(data), `"tags":{"data":[]}`)
+	})
+
+	t.Run("omits empty to-one under option", func(t *testing.T) {
+		data, err := Marshal(article, WithOmitEmptyRelationships())
+		assert.NoError(t, err)
+
+		var doc Document
+		assert.NoError(t, json.Unmarshal(data, &doc))
+		assert.NotContains(t, doc.Data.one.Relationships, "author")
+		assert.Contains(t, doc.Data.one.Relationships, "tags")
+	})
+
+	t.Run("keeps to-one with data", func(t *testing.T) {
+		data, err := Marshal(Article{ID: "1", AuthorID: "1"}, WithOmitEmptyRelationships())
+		assert.NoError(t, err)
+
+		var doc Document
+		assert.NoError(t, json.Unmarshal(data, &doc))
+		assert.Contains(t, doc.Data.one.Relationships, "author")
+	})
+
+	t.Run("keeps empty to-one with links", func(t *testing.T) {
+		data, err := Marshal(article, WithOmitEmptyRelationships(), WithDefaultLinks("http://example.com"))
+		assert.NoError(t, err)
+
+		var doc Document
+		assert.NoError(t, json.Unmarshal(data, &doc))
+		assert.Contains(t, doc.Data.one.Relationships, "author")
+	})
+}
+
+func TestWithErrorTitleMapper(t *testing.T) {
+	mapper := WithErrorTitleMapper(func(status int) string {
+		return fmt.Sprintf("Failure %d", status)
+	})
+	custom := &Error{Status: "409", Title: "Conflict Detected", Detail: "version mismatch"}
+
+	for name, opts := range map[string][]Options{
+		"mapper first": {mapper, WithError(http.StatusNotFound, errors.New("missing")), WithError(http.StatusConflict, custom)},
+		"mapper last":  {WithError(http.StatusNotFound, errors.New("missing")), WithError(http.StatusConflict, custom), mapper},
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := Marshal(nil, opts...)
+			require.NoError(t, err)
+
+			var doc Document
+			require.NoError(t, json.Unmarshal(data, &doc))
+			if assert.Len(t, doc.Errors, 2) {
+				assert.Equal(t, "Failure 404", doc.Errors[0].Title)
+				assert.Equal(t, "missing", doc.Errors[0].Detail)
+				assert.Equal(t, "Conflict Detected", doc.Errors[1].Title)
+			}
+		})
+	}
+
+	t.Run("without mapper", func(t *testing.T) {
+		data, err := Marshal(nil, WithError(http.StatusNotFound, errors.New("missing")))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"title":"Not Found"`)
+	})
+}
+
+func TestWithIncludedMeta(t *testing.T) {
+	article := Article{ID: "1", AuthorID: "1", TagIDs: []string{"1"}}
+
+	data, err := Marshal(article, WithIncludedMeta(func(path string, id ResourceIdentifier) map[string]interface{} {
+		if id.ResourceType() == "tags" {
+			return nil
+		}
+		return map[string]interface{}{"includedVia": path}
+	}))
+	require.NoError(t, err)
+
+	var doc Document
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Nil(t, doc.Data.one.Meta)
+	if assert.Len(t, doc.Included, 2) {
+		assert.Equal(t, "users", doc.Included[0].Type)
+		assert.Equal(t, map[string]interface{}{"includedVia": "author"}, doc.Included[0].Meta)
+		assert.Equal(t, "tags", doc.Included[1].Type)
+		assert.Nil(t, doc.Included[1].Meta)
+	}
+}
+
+// kebabComment declares a camel-case relationship name.
+type kebabComment struct {
+	ID       string `json:"-"`
+	Body     string `json:"body"`
+	BlogPost string `json:"-"`
+}
+
+func (c kebabComment) ResourceID() string   { return c.ID }
+func (c kebabComment) ResourceType() string { return "comments" }
+
+func (c *kebabComment) SetResourceID(id string) error {
+	c.ID = id
+	return nil
+}
+
+func (c kebabComment) Relationships() map[string]RelationType {
+	return map[string]RelationType{"blogPost": RelationToOne}
+}
+
+func (c kebabComment) MarshalRef(name string) []ResourceIdentifier {
+	if name == "blogPost" && c.BlogPost != "" {
+		return OneRef(Ref{Type: "posts", ID: c.BlogPost})
+	}
+	return nil
+}
+
+func (c *kebabComment) UnmarshalRef(name, id string, meta map[string]interface{}) error {
+	if name == "blogPost" {
+		c.BlogPost = id
+	}
+	return nil
+}
+
+func TestWithRelationshipNameMapper(t *testing.T) {
+	kebab := WithRelationshipNameMapper(func(name string) string {
+		var b strings.Builder
+		for _, r := range name {
+			if r >= 'A' && r <= 'Z' {
+				b.WriteByte('-')
+				r += 'a' - 'A'
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	})
+
+	data, err := Marshal(&kebabComment{ID: "1", Body: "Nice", BlogPost: "7"}, kebab, WithMaxIncludeDepth(0), WithoutJSONAPIObject())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"data": {
+			"id": "1",
+			"type": "comments",
+			"attributes": {"body": "Nice"},
+			"relationships": {"blog-post": {"data": {"id": "7", "type": "posts"}}}
+		}
+	}`, string(data))
+
+	var comment kebabComment
+	require.NoError(t, Unmarshal(data, &comment, kebab))
+	assert.Equal(t, kebabComment{ID: "1", Body: "Nice", BlogPost: "7"}, comment)
+
+	t.Run("relationship links use the mapped key", func(t *testing.T) {
+		data, err := Marshal(&kebabComment{ID: "1", BlogPost: "7"}, kebab,
+			WithMaxIncludeDepth(0), WithDefaultLinks("http://example.com"))
+		require.NoError(t, err)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(data, &doc))
+		rel := doc.Data.one.Relationships["blog-post"]
+		if assert.NotNil(t, rel) {
+			assert.Equal(t, "http://example.com/comments/1/relationships/blog-post", rel.Links["self"].Href)
+			assert.Equal(t, "http://example.com/comments/1/blog-post", rel.Links["related"].Href)
+		}
+	})
+}
+
+func TestSortErrorsBy(t *testing.T) {
+	data, err := Marshal(nil,
+		WithError(http.StatusBadRequest, errors.New("first bad request")),
+		WithError(http.StatusInternalServerError, errors.New("server")),
+		WithError(http.StatusBadRequest, errors.New("second bad request")),
+		WithError(http.StatusNotFound, errors.New("missing")),
+		SortErrorsBy(func(a, b Error) bool { return a.Status > b.Status }),
+	)
+	require.NoError(t, err)
+
+	var doc Document
+	require.NoError(t, json.Unmarshal(data, &doc))
+
+	details := make([]string, len(doc.Errors))
+	for i, e := range doc.Errors {
+		details[i] = e.Detail
+	}
+	assert.Equal(t, []string{"server", "missing", "first bad request", "second bad request"}, details)
+}
+
+// tagLinksArticle provides links for its tags relationship.
+type tagLinksArticle struct {
+	Article
+}
+
+func (a tagLinksArticle) MarshalRefLinks(name string) map[string]Link {
+	if name == "tags" {
+		return map[string]Link{"related": {Href: "/articles/" + a.ID + "/tags"}}
+	}
+	return nil
+}
+
+func TestWithRelationshipsAsLinks(t *testing.T) {
+	article := tagLinksArticle{Article{ID: "1", AuthorID: "1", TagIDs: []string{"1"}}}
+
+	marshal := func(t *testing.T, target string) Document {
+		req := httptest.NewRequest("GET", target, nil)
+		data, err := Marshal(article, WithRequest(req), WithRelationshipsAsLinks())
+		require.NoError(t, err)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(data, &doc))
+		return doc
+	}
+
+	t.Run("relationship not included is links only", func(t *testing.T) {
+		doc := marshal(t, "/articles/1?include=author")
+		tags := doc.Data.one.Relationships["tags"]
+		assert.Nil(t, tags.Data)
+		assert.Equal(t, "/articles/1/tags", tags.Links["related"].Href)
+
+		// author has no links, so its linkage is kept
+		assert.Equal(t, "1", doc.Data.one.Relationships["author"].Data.one.ID)
+		assert.Len(t, doc.Included, 1)
+	})
+
+	t.Run("included relationship keeps linkage", func(t *testing.T) {
+		doc := marshal(t, "/articles/1?include=tags")
+		tags := doc.Data.one.Relationships["tags"]
+		if assert.NotNil(t, tags.Data) {
+			assert.Len(t, tags.Data.many, 1)
+		}
+		assert.NotEmpty(t, tags.Links)
+	})
+}
+
+// untypedResource reads its type from a field that may be left unset.
+type untypedResource struct {
+	ID   string `json:"-"`
+	Kind string `json:"-"`
+}
+
+func (r untypedResource) ResourceID() string   { return r.ID }
+func (r untypedResource) ResourceType() string { return r.Kind }
+
+func TestWithRequireType(t *testing.T) {
+	t.Run("missing type", func(t *testing.T) {
+		_, err := Marshal(untypedResource{ID: "1"}, WithRequireType())
+		assert.EqualError(t, err, "resource jsonapi.untypedResource has no type")
+	})
+
+	t.Run("missing type in collection", func(t *testing.T) {
+		_, err := Marshal([]untypedResource{{ID: "1", Kind: "things"}, {ID: "2"}}, WithRequireType())
+		assert.Error(t, err)
+	})
+
+	t.Run("typed resource", func(t *testing.T) {
+		_, err := Marshal(untypedResource{ID: "1", Kind: "things"}, WithRequireType())
+		assert.NoError(t, err)
+	})
+
+	t.Run("lenient by default", func(t *testing.T) {
+		_, err := Marshal(untypedResource{ID: "1"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("missing type in relationship linkage", func(t *testing.T) {
+		owner := untypedOwner{ID: "1", Items: []untypedResource{{ID: "2", Kind: "things"}, {ID: "3"}}}
+		_, err := Marshal(owner, WithRequireType(), WithMaxIncludeDepth(0))
+		assert.EqualError(t, err, "relationship items: related resource jsonapi.untypedResource has no type")
+
+		_, err = Marshal(owner, WithMaxIncludeDepth(0))
+		assert.NoError(t, err)
+	})
+}
+
+// untypedOwner relates to resources that may be missing their type.
+type untypedOwner struct {
+	ID    string            `json:"-"`
+	Items []untypedResource `json:"-"`
+}
+
+func (o untypedOwner) ResourceID() string   { return o.ID }
+func (o untypedOwner) ResourceType() string { return "owners" }
+
+func (o untypedOwner) Relationships() map[string]RelationType {
+	return map[string]RelationType{"items": RelationToMany}
+}
+
+func (o untypedOwner) MarshalRef(name string) []ResourceIdentifier {
+	return ManyRef(o.Items...)
+}
+
+func TestWithEmptyRelationshipsObject(t *testing.T) {
+	t.Run("emitted for resources without relationships", func(t *testing.T) {
+		data, err := Marshal(testResource{ID: "1", Name: "test"}, WithEmptyRelationshipsObject(), WithoutJSONAPIObject())
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"data": {
+			"id": "1", "type": "test",
+			"attributes": {"ID": "1", "Name": "test"},
+			"relationships": {}
+		}}`, string(data))
+	})
+
+	t.Run("emitted for included resources", func(t *testing.T) {
+		data, err := Marshal(Article{ID: "1", AuthorID: "1"}, WithEmptyRelationshipsObject())
+		require.NoError(t, err)
+
+		var raw struct {
+			Included []map[string]json.RawMessage `json:"included"`
+		}
+		require.NoError(t, json.Unmarshal(data, &raw))
+		require.Len(t, raw.Included, 1)
+		assert.JSONEq(t, `{}`, string(raw.Included[0]["relationships"]))
+	})
+
+	t.Run("omitted by default", func(t *testing.T) {
+		data, err := Marshal(testResource{ID: "1"})
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "relationships")
+	})
+
+	t.Run("existing relationships are kept", func(t *testing.T) {
+		data, err := Marshal(Article{ID: "1", AuthorID: "1"}, WithEmptyRelationshipsObject(), WithMaxIncludeDepth(0))
+		require.NoError(t, err)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(data, &doc))
+		assert.Equal(t, "1", doc.Data.one.Relationships["author"].Data.one.ID)
+	})
+}
+
+func TestWithCollectionCount(t *testing.T) {
+	meta := func(t *testing.T, data interface{}, opts ...Options) map[string]interface{} {
+		t.Helper()
+		out, err := Marshal(data, append(opts, WithCollectionCount(), WithMaxIncludeDepth(0))...)
+		require.NoError(t, err)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(out, &doc))
+		return doc.Meta
+	}
+
+	t.Run("matches collection length", func(t *testing.T) {
+		articles := []Article{{ID: "1"}, {ID: "2"}, {ID: "3"}}
+		assert.Equal(t, float64(3), meta(t, articles)["count"])
+	})
+
+	t.Run("empty collection", func(t *testing.T) {
+		assert.Equal(t, float64(0), meta(t, []Article{})["count"])
+	})
+
+	t.Run("kept alongside other meta", func(t *testing.T) {
+		got := meta(t, []Article{{ID: "1"}}, WithTopMeta("total", 10))
+		assert.Equal(t, map[string]interface{}{"count": float64(1), "total": float64(10)}, got)
+	})
+
+	t.Run("single resource unaffected", func(t *testing.T) {
+		assert.NotContains(t, meta(t, Article{ID: "1"}), "count")
+	})
+}
@@ -0,0 +1,109 @@
+package jsonapi
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// Paginator defines the interface for generating top-level pagination links
+// from the primary data of a marshaled [Document].
+type Paginator interface {
+	// PaginationLinks returns the pagination links (e.g., "next", "prev") for the document.
+	PaginationLinks(doc *Document) map[string]Link
+}
+
+// WithPaginator adds the pagination links generated by the [Paginator] to the
+// top-level links object of the document. Pagination links are merged with, and
+// take precedence over, links added through [WithTopLink].
+//
+// Example:
+//
+//	ctx.Marshal(w, http.StatusOK, articles, jsonapi.WithPaginator(jsonapi.CursorPaginator{
+//		BaseURL: "https://api.example.com/articles",
+//		Size:    25,
+//		Cursor:  func(res jsonapi.Resource) string { return res.ID },
+//	}))
+func WithPaginator(p Paginator) Options {
+	return optionsFunc(func(opts *options) {
+		opts.paginator = p
+	})
+}
+
+// CursorPaginator is a [Paginator] that derives cursor pagination links from the
+// resources in a collection document. The "next" link points after the cursor of the
+// last resource, and the "prev" link points before the cursor of the first resource,
+// using the same page[{label}]=X&page[size]=Y parameters produced by [WithPageCursor].
+type CursorPaginator struct {
+	BaseURL     string                    // URL of the collection; existing query parameters are preserved
+	Size        int                       // Page size emitted as page[size], typically read from the request
+	DefaultSize int                       // Page size used when Size is zero; zero omits the parameter
+	Cursor      func(res Resource) string // Extracts the cursor of a resource
+	NextLabel   string                    // Page parameter label for the next cursor; defaults to "after"
+	PrevLabel   string                    // Page parameter label for the previous cursor; defaults to "before"
+}
+
+// PaginationLinks builds the "next" and "prev" links from the first and last resources
+// of the document's primary data. No links are generated for documents that are not
+// collections, or for empty collections. When a page size is set and the collection holds
+// fewer resources than it, the collection is treated as the last page and no "next" link is
+// generated. The page size is Size, or DefaultSize when the request omitted it.
+func (p CursorPaginator) PaginationLinks(doc *Document) map[string]Link {
+	if doc.Data == nil || !doc.Data.isMany || len(doc.Data.many) == 0 || p.Cursor == nil {
+		return nil
+	}
+
+	var (
+		first = doc.Data.many[0]
+		last  = doc.Data.many[len(doc.Data.many)-1]
+		next  = labelOrDefault(p.NextLabel, "after")
+		prev  = labelOrDefault(p.PrevLabel, "before")
+		size  = p.pageSize()
+		links = make(map[string]Link)
+	)
+
+	if href, ok := p.href(next, p.Cursor(last), prev); ok && (size == 0 || len(doc.Data.many) >= size) {
+		links["next"] = Link{Href: href}
+	}
+	if href, ok := p.href(prev, p.Cursor(first), next); ok {
+		links["prev"] = Link{Href: href}
+	}
+
+	return links
+}
+
+// href builds a page link setting the page[label] cursor and removing the opposing cursor.
+func (p CursorPaginator) href(label, cursor, opposite string) (string, bool) {
+	if cursor == "" {
+		return "", false
+	}
+
+	u, err := url.Parse(p.BaseURL)
+	if err != nil {
+		return "", false
+	}
+
+	query := u.Query()
+	query.Del(pageParam(opposite))
+	query.Set(pageParam(label), cursor)
+	if size := p.pageSize(); size > 0 {
+		query.Set(pageParam("size"), strconv.Itoa(size))
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), true
+}
+
+// pageSize returns the page size of the links, falling back to DefaultSize.
+func (p CursorPaginator) pageSize() int {
+	if p.Size > 0 {
+		return p.Size
+	}
+	return p.DefaultSize
+}
+
+// labelOrDefault returns the label, or the fallback if the label is empty.
+func labelOrDefault(label, fallback string) string {
+	if label == "" {
+		return fallback
+	}
+	return label
+}
@@ -0,0 +1,97 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorPaginator_PaginationLinks(t *testing.T) {
+	page := func(ids ...string) *Document {
+		data := &DocumentData{isMany: true}
+		for _, id := range ids {
+			data.many = append(data.many, Resource{Type: "articles", ID: id})
+		}
+		return &Document{Data: data}
+	}
+
+	paginator := CursorPaginator{
+		BaseURL: "http://example.com/articles?sort=title",
+		Size:    3,
+		Cursor:  func(res Resource) string { return "c" + res.ID },
+	}
+
+	t.Run("full page", func(t *testing.T) {
+		links := paginator.PaginationLinks(page("1", "2", "3"))
+		assert.Equal(t, map[string]Link{
+			"next": {Href: "http://example.com/articles?page%5Bafter%5D=c3&page%5Bsize%5D=3&sort=title"},
+			"prev": {Href: "http://example.com/articles?page%5Bbefore%5D=c1&page%5Bsize%5D=3&sort=title"},
+		}, links)
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		links := paginator.PaginationLinks(page("4"))
+		assert.NotContains(t, links, "next")
+		assert.Contains(t, links, "prev")
+	})
+
+	t.Run("custom labels replace opposing cursor", func(t *testing.T) {
+		p := CursorPaginator{
+			BaseURL:   "http://example.com/articles?page[older]=c9",
+			Cursor:    func(res Resource) string { return res.ID },
+			NextLabel: "older",
+			PrevLabel: "newer",
+		}
+		links := p.PaginationLinks(page("1", "2"))
+		assert.Equal(t, "http://example.com/articles?page%5Bolder%5D=2", links["next"].Href)
+		assert.Equal(t, "http://example.com/articles?page%5Bnewer%5D=1", links["prev"].Href)
+	})
+
+	t.Run("omitted size uses default", func(t *testing.T) {
+		p := CursorPaginator{
+			BaseURL:     "http://example.com/articles",
+			DefaultSize: 2,
+			Cursor:      func(res Resource) string { return res.ID },
+		}
+		links := p.PaginationLinks(page("1", "2"))
+		assert.Equal(t, "http://example.com/articles?page%5Bafter%5D=2&page%5Bsize%5D=2", links["next"].Href)
+		assert.Equal(t, "http://example.com/articles?page%5Bbefore%5D=1&page%5Bsize%5D=2", links["prev"].Href)
+
+		// a requested size takes precedence over the default
+		p.Size = 5
+		links = p.PaginationLinks(page("1", "2"))
+		assert.NotContains(t, links, "next")
+		assert.Equal(t, "http://example.com/articles?page%5Bbefore%5D=1&page%5Bsize%5D=5", links["prev"].Href)
+	})
+
+	t.Run("empty collection", func(t *testing.T) {
+		assert.Empty(t, paginator.PaginationLinks(page()))
+	})
+
+	t.Run("single resource", func(t *testing.T) {
+		doc := &Document{Data: &DocumentData{one: Resource{Type: "articles", ID: "1"}}}
+		assert.Empty(t, paginator.PaginationLinks(doc))
+	})
+}
+
+func TestWithPaginator(t *testing.T) {
+	articles := []Article{{ID: "1"}, {ID: "2"}}
+
+	data, err := Marshal(articles,
+		WithTopHref("self", "http://example.com/articles"),
+		WithPaginator(CursorPaginator{
+			BaseURL: "http://example.com/articles",
+			Size:    2,
+			Cursor:  func(res Resource) string { return res.ID },
+		}),
+	)
+	require.NoError(t, err)
+
+	var doc Document
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "http://example.com/articles", doc.Links["self"].Href)
+	assert.Equal(t, "http://example.com/articles?page%5Bafter%5D=2&page%5Bsize%5D=2", doc.Links["next"].Href)
+	assert.Equal(t, "http://example.com/articles?page%5Bbefore%5D=1&page%5Bsize%5D=2", doc.Links["prev"].Href)
+}
@@ -0,0 +1,370 @@
+package jsonapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// GetQueryParam returns the raw value of the named query parameter from the request URL.
+// It returns an empty string if the parameter is not present.
+func (c *Context) GetQueryParam(r *http.Request, key string) string {
+	return r.URL.Query().Get(key)
+}
+
+// GetQueryInt returns the named query parameter parsed as an integer.
+// It returns zero and no error if the parameter is not present. If the value
+// is not a valid integer, the returned error is a JSON:API [Error] with a 400 status
+// and a source parameter pointing to the offending query parameter, suitable for
+// passing directly to [Context.MarshalErrors].
+func (c *Context) GetQueryInt(r *http.Request, key string) (int, error) {
+	value := c.GetQueryParam(r, key)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, parameterError(key, fmt.Sprintf("query parameter %s must be an integer, got %q", key, value))
+	}
+	return n, nil
+}
+
+// GetQueryBool returns the named query parameter parsed as a boolean.
+// It returns false and no error if the parameter is not present. If the value
+// is not a valid boolean, the returned error is a JSON:API [Error] with a 400 status.
+func (c *Context) GetQueryBool(r *http.Request, key string) (bool, error) {
+	value := c.GetQueryParam(r, key)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, parameterError(key, fmt.Sprintf("query parameter %s must be a boolean, got %q", key, value))
+	}
+	return b, nil
+}
+
+// GetPageParam returns the raw value of the page[key] query parameter.
+// It returns an empty string if the parameter is not present.
+//
+// Example:
+//
+//	// GET /articles?page[cursor]=abc123
+//	cursor := ctx.GetPageParam(r, "cursor") // "abc123"
+func (c *Context) GetPageParam(r *http.Request, key string) string {
+	return c.GetQueryParam(r, pageParam(key))
+}
+
+// GetPageInt returns the page[key] query parameter parsed as an integer.
+// It returns zero and no error if the parameter is not present. Non-numeric values
+// such as page[number]=abc produce a JSON:API [Error] with a 400 status:
+//
+//	number, err := ctx.GetPageInt(r, "number")
+//	if err != nil {
+//		ctx.MarshalErrors(w, http.StatusBadRequest, err)
+//		return
+//	}
+func (c *Context) GetPageInt(r *http.Request, key string) (int, error) {
+	return c.GetQueryInt(r, pageParam(key))
+}
+
+// GetFilterParam returns the raw value of the filter[key] query parameter.
+// It returns an empty string if the parameter is not present.
+func (c *Context) GetFilterParam(r *http.Request, key string) string {
+	return c.GetQueryParam(r, filterParam(key))
+}
+
+// GetFilterValues returns the filter[key] query parameter as a list of comma-separated
+// values, trimming whitespace and dropping empty elements. It returns nil if the parameter
+// is not present.
+//
+// Example:
+//
+//	// GET /articles?filter[status]=active,pending
+//	statuses := ctx.GetFilterValues(r, "status") // ["active", "pending"]
+func (c *Context) GetFilterValues(r *http.Request, key string) []string {
+	value := c.GetFilterParam(r, key)
+	if value == "" {
+		return nil
+	}
+	return splitList(value)
+}
+
+// GetSort returns the fields of the sort query parameter, prefixed with "-" when sorted in
+// descending order. It returns nil and no error if the parameter is not present. Malformed
+// fields, such as "--created" or "+title", are reported as a [MultiError] of 400 Bad Request
+// errors suitable for passing directly to [Context.MarshalErrors].
+//
+// Example:
+//
+//	// GET /articles?sort=-created,title
+//	fields, err := ctx.GetSort(r) // ["-created", "title"]
+func (c *Context) GetSort(r *http.Request) ([]string, error) {
+	if _, ok := r.URL.Query()["sort"]; !ok {
+		return nil, nil
+	}
+	return ParseSort(c.GetQueryParam(r, "sort"))
+}
+
+// ParseSort splits a sort query parameter value into its fields and validates their syntax.
+// Each field may be prefixed with a single "-" for descending order; any other direction
+// prefix is malformed. Fields with leading whitespace are malformed too, since an unencoded
+// "+" prefix reaches the decoded query value as a space. See [Context.GetSort].
+func ParseSort(value string) ([]string, error) {
+	var (
+		fields = []string{}
+		errs   MultiError
+	)
+	for _, raw := range strings.Split(value, ",") {
+		field := strings.TrimSpace(raw)
+		if field == "" {
+			continue
+		}
+		fields = append(fields, field)
+
+		name := strings.TrimPrefix(field, "-")
+		if name == "" || strings.HasPrefix(name, "-") || strings.HasPrefix(name, "+") ||
+			strings.TrimLeft(raw, " \t") != raw {
+			errs = append(errs, parameterError("sort", fmt.Sprintf("malformed sort field %q", raw)))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return fields, nil
+}
+
+// GetFilterParams parses the filter query parameters into a map of field names to operators
+// to values. Two-level keys such as filter[age][gte]=18 are stored under their operator,
+// and single-level keys such as filter[status]=active under the empty operator. A field may
+// appear with several operators. Malformed keys are ignored.
+//
+// Example:
+//
+//	// GET /people?filter[age][gte]=18&filter[age][lt]=65&filter[status]=active
+//	filters := ctx.GetFilterParams(r)
+//	filters["age"]["gte"] // "18"
+//	filters["age"]["lt"]  // "65"
+//	filters["status"][""] // "active"
+func (c *Context) GetFilterParams(r *http.Request) map[string]map[string]string {
+	filters := make(map[string]map[string]string)
+	for key, values := range r.URL.Query() {
+		field, operator, ok := parseFilterKey(key)
+		if !ok || len(values) == 0 {
+			continue
+		}
+		if filters[field] == nil {
+			filters[field] = make(map[string]string)
+		}
+		filters[field][operator] = values[0]
+	}
+	return filters
+}
+
+// parseFilterKey splits a filter[field] or filter[field][operator] query parameter key.
+// It reports false for keys that are not well-formed filter keys.
+func parseFilterKey(key string) (field, operator string, ok bool) {
+	rest, found := strings.CutPrefix(key, "filter[")
+	if !found {
+		return "", "", false
+	}
+	field, rest, found = strings.Cut(rest, "]")
+	if !found || field == "" || strings.ContainsAny(field, "[]") {
+		return "", "", false
+	}
+	if rest == "" {
+		return field, "", true
+	}
+
+	rest, found = strings.CutPrefix(rest, "[")
+	if !found {
+		return "", "", false
+	}
+	operator, rest, found = strings.Cut(rest, "]")
+	if !found || operator == "" || rest != "" || strings.Contains(operator, "[") {
+		return "", "", false
+	}
+	return field, operator, true
+}
+
+// Fields returns the sparse fieldset requested for the resource type through the
+// fields[type] query parameter. It returns nil if no fieldset was requested for the type,
+// and an empty slice if the parameter was present but empty.
+//
+// Example:
+//
+//	// GET /articles?fields[articles]=title,body
+//	fields := ctx.Fields(r, "articles") // ["title", "body"]
+func (c *Context) Fields(r *http.Request, resourceType string) []string {
+	return parseFields(r.URL.Query())[resourceType]
+}
+
+// QueryParams holds the JSON:API query parameters of a request, as returned by
+// [Context.QueryParams].
+type QueryParams struct {
+	Include []string            // Relationship paths from the include parameter; nil if absent, empty if include=
+	Fields  map[string][]string // Sparse fieldsets by resource type from fields[type] parameters
+	Sort    []string            // Sort fields from the sort parameter, "-" prefixed when descending
+	Page    map[string]string   // Pagination values by key from page[key] parameters
+	Filter  map[string]string   // Filter values by key from filter[key] parameters
+}
+
+// QueryParams parses the include, fields, sort, page, and filter query parameters of
+// the request into a single [QueryParams] value. Maps are always non-nil. Include is nil
+// when the include parameter is absent and empty when it is present but empty, which
+// per the specification means no related resources should be included. Nested keys such
+// as filter[age][gte] are not part of Page or Filter; use [Context.GetFilterParams] for
+// filter operators.
+//
+// Example:
+//
+//	// GET /articles?include=author&sort=-created&page[size]=10&filter[status]=published
+//	params := ctx.QueryParams(r)
+//	params.Include          // ["author"]
+//	params.Sort             // ["-created"]
+//	params.Page["size"]     // "10"
+//	params.Filter["status"] // "published"
+func (c *Context) QueryParams(r *http.Request) QueryParams {
+	query := r.URL.Query()
+	params := QueryParams{
+		Fields: parseFields(query),
+		Sort:   splitList(query.Get("sort")),
+		Page:   parseBracketed(query, "page"),
+		Filter: parseBracketed(query, "filter"),
+	}
+	if _, ok := query["include"]; ok {
+		params.Include = splitList(query.Get("include"))
+	}
+	return params
+}
+
+// WithRequest makes marshaling aware of the JSON:API query parameters of the request.
+// Sparse fieldsets requested through fields[type] query parameters are applied to the
+// attributes and relationships of every marshaled resource of that type, including
+// related resources in the included array.
+//
+// When the request has an include query parameter, only the listed relationship paths
+// are included; other relationships are emitted with resource linkage only. Nested paths
+// such as comments.author also include their intermediate relationships. Without the
+// parameter, all related resources are included as usual.
+//
+// Example:
+//
+//	// GET /articles/1?fields[articles]=title,author&include=author
+//	ctx.Marshal(w, http.StatusOK, article, jsonapi.WithRequest(r))
+func WithRequest(r *http.Request) Options {
+	return optionsFunc(func(opts *options) {
+		query := r.URL.Query()
+		opts.sparseFields = parseFields(query)
+		opts.includePaths = parseInclude(query)
+	})
+}
+
+// parseFields extracts the sparse fieldsets from fields[type] query parameters.
+func parseFields(query url.Values) map[string][]string {
+	fields := make(map[string][]string)
+	for key, values := range query {
+		if !strings.HasPrefix(key, "fields[") || !strings.HasSuffix(key, "]") {
+			continue
+		}
+		resourceType := key[len("fields[") : len(key)-1]
+		if resourceType == "" || len(values) == 0 {
+			continue
+		}
+		fields[resourceType] = splitList(values[0])
+	}
+	return fields
+}
+
+// parseBracketed extracts the values of family[key] query parameters by key. Nested or
+// malformed keys, such as filter[age][gte], are skipped.
+func parseBracketed(query url.Values, family string) map[string]string {
+	params := make(map[string]string)
+	prefix := family + "["
+	for key, values := range query {
+		if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, "]") {
+			continue
+		}
+		name := key[len(prefix) : len(key)-1]
+		if name == "" || strings.ContainsAny(name, "[]") || len(values) == 0 {
+			continue
+		}
+		params[name] = values[0]
+	}
+	return params
+}
+
+// WithIncludePaths limits the related resources included in the document to those reached
+// through the provided dot-separated relationship paths, such as "author" or
+// "comments.author". Intermediate relationships of nested paths are included as well, and
+// paths that match no relationship are ignored. Related resources are deduplicated by type
+// and ID, so resources reached through several paths, or through cycles, appear once.
+// Without this option, or [WithRequest] with an include query parameter, all related
+// resources are included.
+//
+// Example:
+//
+//	jsonapi.Marshal(post, jsonapi.WithIncludePaths("author", "comments.author"))
+func WithIncludePaths(paths ...string) Options {
+	return optionsFunc(func(opts *options) {
+		opts.includePaths = includePaths(paths)
+	})
+}
+
+// parseInclude extracts the relationship paths requested through the include query
+// parameter, along with every intermediate path. It returns nil if the parameter is absent.
+func parseInclude(query url.Values) map[string]bool {
+	if _, ok := query["include"]; !ok {
+		return nil
+	}
+	return includePaths(splitList(query.Get("include")))
+}
+
+// includePaths returns the set of the provided include paths and their intermediate paths.
+func includePaths(list []string) map[string]bool {
+	paths := make(map[string]bool)
+	for _, path := range list {
+		for i, c := range path {
+			if c == '.' {
+				paths[path[:i]] = true
+			}
+		}
+		paths[path] = true
+	}
+	return paths
+}
+
+// splitList splits a comma-separated query parameter value, trimming whitespace
+// and dropping empty elements. It always returns a non-nil slice.
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// pageParam returns the query parameter name for the given page key.
+func pageParam(key string) string {
+	return fmt.Sprintf("page[%s]", key)
+}
+
+// filterParam returns the query parameter name for the given filter key.
+func filterParam(key string) string {
+	return fmt.Sprintf("filter[%s]", key)
+}
+
+// parameterError creates a 400 Bad Request JSON:API [Error] whose source
+// points to the provided query parameter.
+func parameterError(param, detail string) *Error {
+	return &Error{
+		Status: strconv.Itoa(http.StatusBadRequest),
+		Title:  http.StatusText(http.StatusBadRequest),
+		Detail: detail,
+		Source: ErrorSource{Parameter: param},
+	}
+}
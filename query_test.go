@@ -0,0 +1,438 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContext_GetPageInt(t *testing.T) {
+	ctx := &Context{}
+
+	t.Run("valid numeric page params", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/articles?page[number]=2&page[size]=25", nil)
+
+		number, err := ctx.GetPageInt(req, "number")
+		require.NoError(t, err)
+		assert.Equal(t, 2, number)
+
+		size, err := ctx.GetPageInt(req, "size")
+		require.NoError(t, err)
+		assert.Equal(t, 25, size)
+	})
+
+	t.Run("missing page param", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/articles", nil)
+
+		number, err := ctx.GetPageInt(req, "number")
+		require.NoError(t, err)
+		assert.Equal(t, 0, number)
+	})
+
+	t.Run("non-numeric page param", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/articles?page[number]=abc", nil)
+
+		_, err := ctx.GetPageInt(req, "number")
+		require.Error(t, err)
+
+		var jsonErr *Error
+		require.True(t, errors.As(err, &jsonErr))
+		assert.Equal(t, "400", jsonErr.Status)
+		assert.Equal(t, "page[number]", jsonErr.Source.Parameter)
+	})
+
+	t.Run("error renders as 400 document", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/articles?page[size]=ten", nil)
+		w := httptest.NewRecorder()
+
+		_, err := ctx.GetPageInt(req, "size")
+		require.Error(t, err)
+
+		_, werr := ctx.MarshalErrors(w, http.StatusBadRequest, err)
+		require.NoError(t, werr)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"parameter":"page[size]"`)
+	})
+}
+
+func TestContext_GetPageParam(t *testing.T) {
+	ctx := &Context{}
+	req := httptest.NewRequest("GET", "/articles?page[cursor]=abc123", nil)
+
+	assert.Equal(t, "abc123", ctx.GetPageParam(req, "cursor"))
+	assert.Equal(t, "", ctx.GetPageParam(req, "size"))
+}
+
+func TestContext_GetFilterValues(t *testing.T) {
+	ctx := &Context{}
+	req := httptest.NewRequest("GET", "/articles?filter[status]=active,%20pending,&filter[author]=1", nil)
+
+	assert.Equal(t, "active, pending,", ctx.GetFilterParam(req, "status"))
+	assert.Equal(t, []string{"active", "pending"}, ctx.GetFilterValues(req, "status"))
+	assert.Equal(t, []string{"1"}, ctx.GetFilterValues(req, "author"))
+	assert.Nil(t, ctx.GetFilterValues(req, "tag"))
+}
+
+func TestContext_GetQueryBool(t *testing.T) {
+	ctx := &Context{}
+
+	t.Run("valid boolean", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/articles?draft=true", nil)
+		draft, err := ctx.GetQueryBool(req, "draft")
+		require.NoError(t, err)
+		assert.True(t, draft)
+	})
+
+	t.Run("invalid boolean", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/articles?draft=maybe", nil)
+		_, err := ctx.GetQueryBool(req, "draft")
+
+		var jsonErr *Error
+		require.True(t, errors.As(err, &jsonErr))
+		assert.Equal(t, "400", jsonErr.Status)
+		assert.Equal(t, "draft", jsonErr.Source.Parameter)
+	})
+}
+
+func TestContext_Fields(t *testing.T) {
+	ctx := &Context{}
+	req := httptest.NewRequest("GET", "/articles?fields[articles]=title,%20content&fields[users]=&include=author", nil)
+
+	assert.Equal(t, []string{"title", "content"}, ctx.Fields(req, "articles"))
+	assert.Equal(t, []string{}, ctx.Fields(req, "users"))
+	assert.Nil(t, ctx.Fields(req, "tags"))
+}
+
+func TestWithRequest_SparseFieldsets(t *testing.T) {
+	article := Article{ID: "1", Title: "Title", Content: "Content", AuthorID: "1", TagIDs: []string{"1"}}
+
+	marshal := func(t *testing.T, target string) Document {
+		req := httptest.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		_, err := FromContext(req.Context()).Marshal(w, http.StatusOK, article, WithRequest(req))
+		require.NoError(t, err)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		return doc
+	}
+
+	t.Run("without fields", func(t *testing.T) {
+		doc := marshal(t, "/articles/1")
+		assert.JSONEq(t, `{"id":"1","title":"Title","content":"Content"}`, string(doc.Data.one.Attributes))
+		assert.Len(t, doc.Data.one.Relationships, 2)
+	})
+
+	t.Run("applies fieldset to primary data", func(t *testing.T) {
+		doc := marshal(t, "/articles/1?fields[articles]=title,author")
+		assert.JSONEq(t, `{"title":"Title"}`, string(doc.Data.one.Attributes))
+		assert.Contains(t, doc.Data.one.Relationships, "author")
+		assert.NotContains(t, doc.Data.one.Relationships, "tags")
+		if assert.Len(t, doc.Included, 1) {
+			assert.Equal(t, "users", doc.Included[0].Type)
+		}
+	})
+
+	t.Run("applies fieldset to included resources", func(t *testing.T) {
+		doc := marshal(t, "/articles/1?fields[users]=name")
+		for _, res := range doc.Included {
+			if res.Type == "users" {
+				assert.JSONEq(t, `{"name":""}`, string(res.Attributes))
+			}
+		}
+	})
+
+	t.Run("empty fieldset omits attributes", func(t *testing.T) {
+		doc := marshal(t, "/articles/1?fields[articles]=")
+		assert.Nil(t, doc.Data.one.Attributes)
+		assert.Empty(t, doc.Data.one.Relationships)
+	})
+}
+
+func TestWithRequest_Include(t *testing.T) {
+	article := Article{ID: "1", Title: "Title", AuthorID: "1", TagIDs: []string{"1", "2"}}
+
+	marshal := func(t *testing.T, target string) Document {
+		req := httptest.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		_, err := FromContext(req.Context()).Marshal(w, http.StatusOK, article, WithRequest(req))
+		require.NoError(t, err)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		return doc
+	}
+
+	includedTypes := func(doc Document) []string {
+		types := []string{}
+		for _, res := range doc.Included {
+			types = append(types, res.Type)
+		}
+		return types
+	}
+
+	t.Run("without include includes everything", func(t *testing.T) {
+		doc := marshal(t, "/articles/1")
+		assert.Equal(t, []string{"users", "tags", "tags"}, includedTypes(doc))
+	})
+
+	t.Run("includes only listed relationships", func(t *testing.T) {
+		doc := marshal(t, "/articles/1?include=author")
+		assert.Equal(t, []string{"users"}, includedTypes(doc))
+
+		// tags keep their resource linkage
+		assert.Len(t, doc.Data.one.Relationships["tags"].Data.many, 2)
+	})
+
+	t.Run("nested path includes intermediate relationship", func(t *testing.T) {
+		doc := marshal(t, "/articles/1?include=tags.articles")
+		assert.Equal(t, []string{"tags", "tags"}, includedTypes(doc))
+	})
+
+	t.Run("empty include excludes everything", func(t *testing.T) {
+		doc := marshal(t, "/articles/1?include=")
+		assert.Empty(t, doc.Included)
+		assert.Equal(t, "1", doc.Data.one.Relationships["author"].Data.one.ID)
+	})
+}
+
+func TestParseInclude(t *testing.T) {
+	assert.Nil(t, parseInclude(url.Values{}))
+	assert.Equal(t, map[string]bool{
+		"author":          true,
+		"comments":        true,
+		"comments.author": true,
+	}, parseInclude(url.Values{"include": {"author, comments.author"}}))
+}
+
+// blogPost and blogComment form a cycle through the comments and post relationships.
+type blogPost struct {
+	ID         string   `json:"-"`
+	AuthorID   string   `json:"-"`
+	CommentIDs []string `json:"-"`
+}
+
+func (p blogPost) ResourceID() string   { return p.ID }
+func (p blogPost) ResourceType() string { return "posts" }
+
+func (p blogPost) Relationships() map[string]RelationType {
+	return map[string]RelationType{"author": RelationToOne, "comments": RelationToMany}
+}
+
+func (p blogPost) MarshalRef(name string) []ResourceIdentifier {
+	switch name {
+	case "author":
+		return OneRef(users[p.AuthorID])
+	case "comments":
+		var refs []ResourceIdentifier
+		for _, id := range p.CommentIDs {
+			refs = append(refs, blogComment{ID: id, AuthorID: "2", Post: p})
+		}
+		return refs
+	}
+	return nil
+}
+
+type blogComment struct {
+	ID       string   `json:"-"`
+	AuthorID string   `json:"-"`
+	Post     blogPost `json:"-"`
+}
+
+func (c blogComment) ResourceID() string   { return c.ID }
+func (c blogComment) ResourceType() string { return "comments" }
+
+func (c blogComment) Relationships() map[string]RelationType {
+	return map[string]RelationType{"author": RelationToOne, "post": RelationToOne}
+}
+
+func (c blogComment) MarshalRef(name string) []ResourceIdentifier {
+	switch name {
+	case "author":
+		return OneRef(users[c.AuthorID])
+	case "post":
+		return OneRef(c.Post)
+	}
+	return nil
+}
+
+func TestWithIncludePaths(t *testing.T) {
+	post := blogPost{ID: "1", AuthorID: "1", CommentIDs: []string{"1", "2"}}
+
+	included := func(t *testing.T, opts ...Options) []string {
+		data, err := Marshal(post, opts...)
+		require.NoError(t, err)
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(data, &doc))
+		uids := []string{}
+		for _, res := range doc.Included {
+			uids = append(uids, res.Type+":"+res.ID)
+		}
+		return uids
+	}
+
+	t.Run("direct relationship", func(t *testing.T) {
+		assert.Equal(t, []string{"users:1"}, included(t, WithIncludePaths("author")))
+	})
+
+	t.Run("nested path deduplicates shared authors", func(t *testing.T) {
+		assert.Equal(t, []string{"comments:1", "users:2", "comments:2"}, included(t, WithIncludePaths("comments.author")))
+	})
+
+	t.Run("compound paths", func(t *testing.T) {
+		assert.Equal(t,
+			[]string{"users:1", "comments:1", "users:2", "comments:2"},
+			included(t, WithIncludePaths("author", "comments.author")),
+		)
+	})
+
+	t.Run("cycles terminate", func(t *testing.T) {
+		assert.Equal(t,
+			[]string{"comments:1", "comments:2"},
+			included(t, WithIncludePaths("comments.post.comments.post")),
+		)
+	})
+
+	t.Run("unknown paths are ignored", func(t *testing.T) {
+		assert.Equal(t, []string{"users:1"}, included(t, WithIncludePaths("author", "editor", "author.posts")))
+	})
+}
+
+func TestContext_QueryParams(t *testing.T) {
+	ctx := &Context{}
+
+	t.Run("all query aspects", func(t *testing.T) {
+		target := "/articles?include=author,comments.author" +
+			"&fields[articles]=title,body&fields[people]=name" +
+			"&sort=-created,title" +
+			"&page[number]=2&page[size]=25" +
+			"&filter[status]=published&filter[author]=jane" +
+			"&search=ignored"
+		params := ctx.QueryParams(httptest.NewRequest("GET", target, nil))
+
+		assert.Equal(t, QueryParams{
+			Include: []string{"author", "comments.author"},
+			Fields: map[string][]string{
+				"articles": {"title", "body"},
+				"people":   {"name"},
+			},
+			Sort:   []string{"-created", "title"},
+			Page:   map[string]string{"number": "2", "size": "25"},
+			Filter: map[string]string{"status": "published", "author": "jane"},
+		}, params)
+	})
+
+	t.Run("empty include differs from absent include", func(t *testing.T) {
+		params := ctx.QueryParams(httptest.NewRequest("GET", "/articles?include=", nil))
+		assert.NotNil(t, params.Include)
+		assert.Empty(t, params.Include)
+	})
+
+	t.Run("nested and malformed keys are skipped", func(t *testing.T) {
+		target := "/people?filter[age][gte]=18&filter[status]=active&page[a]b]=1&page[size]=10"
+		params := ctx.QueryParams(httptest.NewRequest("GET", target, nil))
+		assert.Equal(t, map[string]string{"status": "active"}, params.Filter)
+		assert.Equal(t, map[string]string{"size": "10"}, params.Page)
+	})
+
+	t.Run("no query parameters", func(t *testing.T) {
+		params := ctx.QueryParams(httptest.NewRequest("GET", "/articles", nil))
+		assert.Nil(t, params.Include)
+		assert.Empty(t, params.Sort)
+		assert.NotNil(t, params.Fields)
+		assert.NotNil(t, params.Page)
+		assert.NotNil(t, params.Filter)
+	})
+}
+
+func TestContext_GetFilterParams(t *testing.T) {
+	ctx := &Context{}
+
+	t.Run("operators and single-level keys", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/people?filter[age][gte]=18&filter[age][lt]=65&filter[status]=active", nil)
+		assert.Equal(t, map[string]map[string]string{
+			"age":    {"gte": "18", "lt": "65"},
+			"status": {"": "active"},
+		}, ctx.GetFilterParams(req))
+
+		// single-level access keeps working
+		assert.Equal(t, "active", ctx.GetFilterParam(req, "status"))
+	})
+
+	t.Run("malformed keys are ignored", func(t *testing.T) {
+		query := url.Values{}
+		for _, key := range []string{
+			"filter[", "filter[]", "filter[age", "filter[age]x", "filter[age][",
+			"filter[age][]", "filter[age][gte]x", "filter[age][gte][x]", "filter[[age]]",
+			"filters[age]", "page[size]",
+		} {
+			query.Set(key, "1")
+		}
+		query.Set("filter[name][eq]", "jane")
+		req := httptest.NewRequest("GET", "/people?"+query.Encode(), nil)
+
+		assert.Equal(t, map[string]map[string]string{"name": {"eq": "jane"}}, ctx.GetFilterParams(req))
+	})
+
+	t.Run("no filters", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/people", nil)
+		assert.Empty(t, ctx.GetFilterParams(req))
+	})
+}
+
+func TestContext_GetSort(t *testing.T) {
+	ctx := &Context{}
+
+	t.Run("valid fields", func(t *testing.T) {
+		fields, err := ctx.GetSort(httptest.NewRequest("GET", "/articles?sort=-created,title", nil))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"-created", "title"}, fields)
+	})
+
+	t.Run("absent", func(t *testing.T) {
+		fields, err := ctx.GetSort(httptest.NewRequest("GET", "/articles", nil))
+		require.NoError(t, err)
+		assert.Nil(t, fields)
+	})
+
+	t.Run("malformed fields", func(t *testing.T) {
+		for _, token := range []string{"--created", "+title", "-", "-+title"} {
+			t.Run(token, func(t *testing.T) {
+				req := httptest.NewRequest("GET", "/articles?"+url.Values{"sort": {"title," + token}}.Encode(), nil)
+				_, err := ctx.GetSort(req)
+
+				var errs MultiError
+				require.ErrorAs(t, err, &errs)
+				require.Len(t, errs, 1)
+				assert.Equal(t, "400", errs[0].Status)
+				assert.Equal(t, "sort", errs[0].Source.Parameter)
+				assert.Contains(t, errs[0].Detail, token)
+			})
+		}
+	})
+
+	t.Run("unencoded plus prefix", func(t *testing.T) {
+		for _, target := range []string{"/articles?sort=+title", "/articles?sort=-created,+title"} {
+			_, err := ctx.GetSort(httptest.NewRequest("GET", target, nil))
+
+			var errs MultiError
+			require.ErrorAs(t, err, &errs, target)
+			require.Len(t, errs, 1)
+			assert.Equal(t, `malformed sort field " title"`, errs[0].Detail)
+		}
+	})
+
+	t.Run("reports every malformed field", func(t *testing.T) {
+		_, err := ParseSort("--a,b,+c")
+		var errs MultiError
+		require.ErrorAs(t, err, &errs)
+		assert.Len(t, errs, 2)
+	})
+}
@@ -0,0 +1,109 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// WithTimeFormat formats time.Time and *time.Time attributes with the provided layout
+// when marshaling, and parses them with the same layout when unmarshaling. An empty layout
+// defaults to [time.RFC3339]. Zero times of fields tagged omitempty are omitted, and nil
+// *time.Time fields are emitted as null. Without this option, times use their default
+// JSON encoding.
+//
+// Example:
+//
+//	jsonapi.Marshal(article, jsonapi.WithTimeFormat(time.DateOnly))
+func WithTimeFormat(layout string) Options {
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	return optionsFunc(func(opts *options) {
+		opts.timeFormat = layout
+	})
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// timeFields returns the time.Time and *time.Time attribute fields of the struct type t
+// keyed by attribute name.
+func timeFields(t reflect.Type) map[string]attributeField {
+	fields := make(map[string]attributeField)
+	for _, field := range attributeFields(t) {
+		fieldType := field.typ
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType == timeType {
+			fields[field.name] = field
+		}
+	}
+	return fields
+}
+
+// formatTimes rewrites the time attributes of the serialized attributes object of v
+// using the layout.
+func formatTimes(attributes []byte, v interface{}, layout string) ([]byte, error) {
+	fields := timeFields(reflect.TypeOf(v))
+	if len(fields) == 0 {
+		return attributes, nil
+	}
+
+	raw := make(map[string]json.RawMessage)
+	if err := jsonUnmarshal(attributes, &raw); err != nil {
+		return nil, fmt.Errorf("format time attributes: %w", err)
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(v))
+	for name, field := range fields {
+		if _, ok := raw[name]; !ok {
+			continue
+		}
+		fieldValue, err := value.FieldByIndexErr(field.index)
+		if err != nil || (fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil()) {
+			continue
+		}
+
+		t := reflect.Indirect(fieldValue).Interface().(time.Time)
+		if t.IsZero() && field.omitEmpty {
+			delete(raw, name)
+			continue
+		}
+		if raw[name], err = jsonMarshal(t.Format(layout)); err != nil {
+			return nil, err
+		}
+	}
+	return jsonMarshal(raw)
+}
+
+// parseTimes rewrites the time attributes of a serialized attributes object formatted
+// with the layout into the default JSON encoding of time.Time expected by target.
+func parseTimes(attributes []byte, target interface{}, layout string) ([]byte, error) {
+	fields := timeFields(reflect.TypeOf(target))
+	if len(fields) == 0 {
+		return attributes, nil
+	}
+
+	raw := make(map[string]json.RawMessage)
+	if err := jsonUnmarshal(attributes, &raw); err != nil {
+		return nil, err
+	}
+
+	for name := range fields {
+		var text string
+		if value, ok := raw[name]; !ok || jsonUnmarshal(value, &text) != nil {
+			// absent, null, or non-string values are left to the JSON decoder.
+			continue
+		}
+		t, err := time.Parse(layout, text)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", name, err)
+		}
+		if raw[name], err = jsonMarshal(t.Format(time.RFC3339Nano)); err != nil {
+			return nil, err
+		}
+	}
+	return jsonMarshal(raw)
+}
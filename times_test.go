@@ -0,0 +1,81 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// event carries time attributes with and without omitempty.
+type event struct {
+	ID        string     `json:"-"`
+	StartsAt  time.Time  `json:"startsAt"`
+	EndsAt    time.Time  `json:"endsAt,omitempty"`
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+func (e event) ResourceID() string   { return e.ID }
+func (e event) ResourceType() string { return "events" }
+
+func (e *event) SetResourceID(id string) error {
+	e.ID = id
+	return nil
+}
+
+func TestWithTimeFormat(t *testing.T) {
+	start := time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC)
+
+	attributes := func(t *testing.T, data []byte) string {
+		var doc Document
+		require.NoError(t, json.Unmarshal(data, &doc))
+		return string(doc.Data.one.Attributes)
+	}
+
+	t.Run("custom layout round trip", func(t *testing.T) {
+		created := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+		in := event{ID: "1", StartsAt: start, EndsAt: start.Add(time.Hour), CreatedAt: &created}
+
+		data, err := Marshal(in, WithTimeFormat(time.DateTime))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"startsAt": "2024-03-15 09:30:00",
+			"endsAt": "2024-03-15 10:30:00",
+			"createdAt": "2024-01-02 00:00:00"
+		}`, attributes(t, data))
+
+		var out event
+		require.NoError(t, Unmarshal(data, &out, WithTimeFormat(time.DateTime)))
+		assert.True(t, in.StartsAt.Equal(out.StartsAt))
+		assert.True(t, in.EndsAt.Equal(out.EndsAt))
+		assert.True(t, in.CreatedAt.Equal(*out.CreatedAt))
+		assert.Nil(t, out.UpdatedAt)
+	})
+
+	t.Run("defaults to RFC3339", func(t *testing.T) {
+		data, err := Marshal(event{ID: "1", StartsAt: start.Add(time.Millisecond)}, WithTimeFormat(""))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"startsAt": "2024-03-15T09:30:00Z", "createdAt": null}`, attributes(t, data))
+	})
+
+	t.Run("zero omitempty time is omitted and nil pointer is null", func(t *testing.T) {
+		data, err := Marshal(event{ID: "1"}, WithTimeFormat(time.DateOnly))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"startsAt": "0001-01-01", "createdAt": null}`, attributes(t, data))
+	})
+
+	t.Run("invalid time", func(t *testing.T) {
+		var out event
+		err := Unmarshal([]byte(`{"data": {"type": "events", "id": "1", "attributes": {"startsAt": "tomorrow"}}}`), &out, WithTimeFormat(time.DateOnly))
+		assert.ErrorContains(t, err, "attribute startsAt")
+	})
+
+	t.Run("default encoding without option", func(t *testing.T) {
+		data, err := Marshal(event{ID: "1", StartsAt: start})
+		require.NoError(t, err)
+		assert.Contains(t, attributes(t, data), `"endsAt":"0001-01-01T00:00:00Z"`)
+	})
+}
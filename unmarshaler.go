@@ -1,9 +1,16 @@
 package jsonapi
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // ResourceUnmarshaler defines the interface that resources must implement
@@ -38,6 +45,14 @@ type RelationshipUnmarshaler interface {
 	UnmarshalRef(name string, id string, meta map[string]interface{}) error
 }
 
+// RelationshipClearer defines the interface for resources that can clear
+// all data of a relationship before it is replaced during unmarshaling.
+type RelationshipClearer interface {
+	RelationshipUnmarshaler
+	// ClearRef removes all data for the named relationship.
+	ClearRef(name string) error
+}
+
 // RelationshipLinksUnmarshaler defines the interface for resources that can receive
 // relationship-specific links during unmarshaling operations.
 type RelationshipLinksUnmarshaler interface {
@@ -54,16 +69,38 @@ type RelationshipMetaUnmarshaler interface {
 	UnmarshalRefMeta(name string, meta map[string]interface{}) error
 }
 
+// ResourceValidator defines the interface for resources that validate themselves once
+// unmarshaling has populated all of their fields, such as to enforce required attributes.
+type ResourceValidator interface {
+	// ValidateJSONAPIResource reports whether the unmarshaled resource is valid.
+	ValidateJSONAPIResource(ctx context.Context) error
+}
+
+// WithValidationContext sets the context passed to [ResourceValidator] implementations
+// during unmarshaling. By default, [context.Background] is used.
+//
+// Example:
+//
+//	err := jsonapi.Unmarshal(body, &article, jsonapi.WithValidationContext(r.Context()))
+func WithValidationContext(ctx context.Context) Options {
+	return optionsFunc(func(opts *options) {
+		opts.validationCtx = ctx
+	})
+}
+
 // UnmarshalData unmarshals the data portion of a JSON:API [Document] into the provided target.
 // The target must be a pointer to a struct or slice that implements the appropriate unmarshaler interfaces.
 func (d Document) UnmarshalData(target interface{}, opts ...Options) error {
 	options := applyOptions(opts)
 
 	if d.Data == nil {
-		return fmt.Errorf(`%w: no data to unmarshal`, io.EOF)
+		return fmt.Errorf("%w: %w", io.EOF, ErrEmptyBody)
 	}
 
 	if d.Data.one.Type != "" {
+		if isInterfacePtr(target) {
+			return unmarshalInterface(d.Data.one, reflect.ValueOf(target).Elem(), &options)
+		}
 		return unmarshalOne(d.Data.one, target, &options)
 	}
 
@@ -71,25 +108,28 @@ func (d Document) UnmarshalData(target interface{}, opts ...Options) error {
 		return unmarshalMany(d.Data.many, target, &options)
 	}
 
-	return fmt.Errorf("%w: no data to unmarshal", io.EOF)
+	return fmt.Errorf("%w: %w", io.EOF, ErrEmptyBody)
 }
 
 // Unmarshal parses JSON:API formatted data and stores the result in the target.
-// The target must be a pointer to a struct or slice that implements [ResourceUnmarshaler].
+// The target must be a pointer to a struct or slice that implements [ResourceUnmarshaler],
+// or a *[Document], which receives the complete document.
 func Unmarshal(data []byte, target interface{}, opts ...Options) error {
 	if target == nil {
-		return fmt.Errorf("target must not be nil")
+		return fmt.Errorf("%w: target must not be nil", ErrNilValue)
 	}
 
 	if reflect.TypeOf(target).Kind() != reflect.Ptr {
-		return fmt.Errorf("target must be a ptr")
+		return fmt.Errorf("%w: target must be a ptr", ErrNotPointer)
 	}
 
-	doc := &Document{}
-	if in, ok := target.(*Document); ok {
-		doc = in
+	// A *Document target receives the raw document, including top-level members
+	// such as jsonapi, meta, and links, without decoding the primary data.
+	if doc, ok := target.(*Document); ok {
+		return jsonUnmarshal(data, doc)
 	}
 
+	doc := &Document{}
 	err := jsonUnmarshal(data, doc)
 	if err != nil {
 		return err
@@ -98,18 +138,47 @@ func Unmarshal(data []byte, target interface{}, opts ...Options) error {
 	return doc.UnmarshalData(target, opts...)
 }
 
+// UnmarshalAs parses a JSON:API document containing a single resource and returns it
+// as a value of type T. A pointer to T must implement [ResourceUnmarshaler].
+//
+// Example:
+//
+//	article, err := jsonapi.UnmarshalAs[Article](data)
+func UnmarshalAs[T any](data []byte, opts ...Options) (T, error) {
+	var target T
+	err := Unmarshal(data, &target, opts...)
+	return target, err
+}
+
+// UnmarshalManyAs parses a JSON:API document containing a resource collection and returns
+// it as a slice of T. A pointer to T must implement [ResourceUnmarshaler].
+//
+// Example:
+//
+//	articles, err := jsonapi.UnmarshalManyAs[Article](data)
+func UnmarshalManyAs[T any](data []byte, opts ...Options) ([]T, error) {
+	var target []T
+	err := Unmarshal(data, &target, opts...)
+	return target, err
+}
+
 // unmarshalMany unmarshals an array of resources into a slice target.
 func unmarshalMany(many []Resource, target interface{}, options *options) error {
 	if target == nil {
-		return fmt.Errorf("unmarshal target must not be nil")
+		return fmt.Errorf("%w: unmarshal target must not be nil", ErrNilValue)
 	}
 
 	if reflect.TypeOf(target).Kind() != reflect.Ptr {
-		return fmt.Errorf("unmarshal target must be a ptr")
+		return fmt.Errorf("%w: unmarshal target must be a ptr", ErrNotPointer)
 	}
 
 	targetSlice := reflect.TypeOf(target).Elem()
 	if targetSlice.Kind() != reflect.Slice {
+		if options.singleFromArray && len(many) == 1 && isInterfacePtr(target) {
+			return unmarshalInterface(many[0], reflect.ValueOf(target).Elem(), options)
+		} else if options.singleFromArray && len(many) == 1 {
+			return unmarshalOne(many[0], target, options)
+		}
 		return fmt.Errorf("cannot unmarshal array to struct target %s", targetSlice)
 	}
 
@@ -117,19 +186,79 @@ func unmarshalMany(many []Resource, target interface{}, options *options) error
 	targetPointer := reflect.ValueOf(target)
 	targetValue := reflect.MakeSlice(targetSlice, 0, len(many))
 
+	var collected MultiError
 	for idx, record := range many {
 		targetRecord := reflect.New(targetType)
-		err := unmarshalOne(record, targetRecord.Interface(), options)
-		if err != nil {
+		var err error
+		if targetType.Kind() == reflect.Interface {
+			err = unmarshalInterface(record, targetRecord.Elem(), options)
+		} else {
+			err = unmarshalOne(record, targetRecord.Interface(), options)
+		}
+		var errs MultiError
+		if errors.As(err, &errs) && options.collectErrors {
+			collected = append(collected, errs.withPointerPrefix("/data", fmt.Sprintf("/data/%d", idx))...)
+			continue
+		} else if err != nil {
 			return fmt.Errorf("unmarshal resource %d: %w", idx, err)
 		}
 		targetValue = reflect.Append(targetValue, targetRecord.Elem())
 	}
+	if len(collected) > 0 {
+		return collected
+	}
 
 	targetPointer.Elem().Set(targetValue)
 	return nil
 }
 
+// TypeRegistry maps resource types to factories of the concrete values they unmarshal
+// into. It allows unmarshaling polymorphic primary data into interface targets.
+type TypeRegistry map[string]func() ResourceUnmarshaler
+
+// WithTypeRegistry resolves the concrete type of resources unmarshaled into interface
+// targets, such as a *Content or *[]Content, from the resource type using the registry.
+// Each resource is unmarshaled into a new value returned by the factory registered for
+// its type, which must implement the target interface.
+//
+// Example:
+//
+//	var content []Content
+//	err := jsonapi.Unmarshal(data, &content, jsonapi.WithTypeRegistry(jsonapi.TypeRegistry{
+//		"articles": func() jsonapi.ResourceUnmarshaler { return &Article{} },
+//		"videos":   func() jsonapi.ResourceUnmarshaler { return &Video{} },
+//	}))
+func WithTypeRegistry(registry TypeRegistry) Options {
+	return optionsFunc(func(opts *options) {
+		opts.typeRegistry = registry
+	})
+}
+
+// isInterfacePtr reports whether the target is a pointer to an interface value.
+func isInterfacePtr(target interface{}) bool {
+	t := reflect.TypeOf(target)
+	return t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Interface
+}
+
+// unmarshalInterface unmarshals a single resource into a new value of the concrete type
+// registered for its resource type, and stores it in the interface value target.
+func unmarshalInterface(one Resource, target reflect.Value, options *options) error {
+	factory, ok := options.typeRegistry[one.Type]
+	if !ok {
+		return fmt.Errorf("no type registered for resource type %q", one.Type)
+	}
+
+	value := factory()
+	if !reflect.TypeOf(value).AssignableTo(target.Type()) {
+		return fmt.Errorf("registered type %T for resource type %q does not implement %s", value, one.Type, target.Type())
+	}
+	if err := unmarshalOne(one, value, options); err != nil {
+		return err
+	}
+	target.Set(reflect.ValueOf(value))
+	return nil
+}
+
 // unmarshalOne unmarshals a single resource into the target struct.
 func unmarshalOne(one Resource, target interface{}, options *options) error {
 	id, ok := target.(ResourceUnmarshaler)
@@ -137,20 +266,59 @@ func unmarshalOne(one Resource, target interface{}, options *options) error {
 		return fmt.Errorf("unmarshal target must implement ResourceUnmarshaler")
 	}
 
-	if options.validateType && id.ResourceType() != one.Type {
-		return fmt.Errorf("resource type mismatch: %s != %s", id.ResourceType(), one.Type)
+	checkType := options.validateType || options.onTypeMismatch != nil
+	if checkType && !options.typesMatch(id.ResourceType(), one.Type) {
+		if options.validateType {
+			return fmt.Errorf("%w: %s != %s", ErrTypeMismatch, id.ResourceType(), one.Type)
+		}
+		if options.onTypeMismatch != nil {
+			options.onTypeMismatch(id.ResourceType(), one.Type)
+		}
 	}
 
-	id.SetResourceID(one.ID)
-	err := jsonUnmarshal(one.Attributes, target)
-	if err != nil {
-		return fmt.Errorf("unmarshal attributes: %w", err)
+	if options.strictIDs && one.ID != "" && one.LID != "" {
+		return fmt.Errorf("resource %q declares both id and lid %q", one.ID, one.LID)
+	}
+
+	allocEmbedded(reflect.ValueOf(target))
+	if len(options.allowedAttrs) > 0 && len(one.Attributes) > 0 {
+		if allowed, ok := options.allowedAttrs[id.ResourceType()]; ok {
+			attributes, err := filterAttributes(one.Attributes, allowed, options.strictAttrs)
+			if err != nil {
+				return err
+			}
+			one.Attributes = attributes
+		}
+	}
+	if err := id.SetResourceID(one.ID); err != nil {
+		return fmt.Errorf("set resource id: %w", err)
+	}
+	if len(one.Attributes) > 0 && options.timeFormat != "" {
+		attributes, err := parseTimes(one.Attributes, target, options.timeFormat)
+		if err != nil {
+			return fmt.Errorf("unmarshal attributes: %w", err)
+		}
+		one.Attributes = attributes
+	}
+	if len(one.Attributes) > 0 {
+		if err := jsonUnmarshal(one.Attributes, target); err != nil {
+			if options.collectErrors {
+				if errs := unmarshalAttributes(one.Attributes, target); len(errs) > 0 {
+					return errs
+				}
+			}
+			return fmt.Errorf("unmarshal attributes: %w", err)
+		}
 	}
 
 	if unmarshaler, ok := id.(LinksUnmarshaler); ok {
 		if err := unmarshaler.UnmarshalLinks(one.Links); err != nil {
 			return fmt.Errorf("unmarshal links: %w", err)
 		}
+	} else if len(one.Links) > 0 {
+		if err := setTaggedField(reflect.ValueOf(target), "links", reflect.ValueOf(one.Links)); err != nil {
+			return err
+		}
 	}
 
 	if unmarshaler, ok := id.(MetaUnmarshaler); ok {
@@ -160,16 +328,157 @@ func unmarshalOne(one Resource, target interface{}, options *options) error {
 	}
 
 	if unmarshaler, ok := id.(RelationshipUnmarshaler); ok {
-		for name, rel := range one.Relationships {
-			if err := unmarshalRelationship(rel, name, unmarshaler, options); err != nil {
-				return fmt.Errorf("unmarshal relationship %s: %w", name, err)
+		names := options.relationshipNames(target)
+		for key, rel := range one.Relationships {
+			name := key
+			if mapped, ok := names[key]; ok {
+				name = mapped
+			}
+			if err := unmarshalRelationship(rel, name, unmarshaler, options); errors.Is(err, ErrReadOnly) {
+				return readOnlyError(key, "/data/relationships/"+key, err)
+			} else if err != nil {
+				return fmt.Errorf("unmarshal relationship %s: %w", key, err)
 			}
 		}
 	}
 
+	if validator, ok := target.(ResourceValidator); ok {
+		if err := validator.ValidateJSONAPIResource(options.validationCtx); err != nil {
+			return fmt.Errorf("validate resource %s %q: %w", one.Type, one.ID, err)
+		}
+	}
+
 	return nil
 }
 
+// unmarshalAttributes decodes each attribute into the target separately, collecting a
+// 422 Unprocessable Entity [Error] pointing at every attribute that fails to decode.
+func unmarshalAttributes(attributes json.RawMessage, target interface{}) MultiError {
+	var members map[string]json.RawMessage
+	if err := jsonUnmarshal(attributes, &members); err != nil {
+		return MultiError{attributeError("/data/attributes", err.Error())}
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs MultiError
+	for _, name := range names {
+		member, err := jsonMarshal(map[string]json.RawMessage{name: members[name]})
+		if err == nil {
+			err = jsonUnmarshal(member, target)
+		}
+		if err != nil {
+			errs = append(errs, attributeError("/data/attributes/"+name, err.Error()))
+		}
+	}
+	return errs
+}
+
+// filterAttributes removes attributes whose names are not in allowed. If strict is true,
+// the first disallowed attribute is instead reported as a 403 Forbidden [Error].
+func filterAttributes(attributes json.RawMessage, allowed map[string]bool, strict bool) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := jsonUnmarshal(attributes, &fields); err != nil {
+		return nil, fmt.Errorf("unmarshal attributes: %w", err)
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		if !allowed[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return attributes, nil
+	}
+
+	sort.Strings(names)
+	if strict {
+		return nil, &Error{
+			Status: strconv.Itoa(http.StatusForbidden),
+			Title:  http.StatusText(http.StatusForbidden),
+			Detail: fmt.Sprintf("attribute %q is not allowed", names[0]),
+			Source: ErrorSource{Pointer: "/data/attributes/" + names[0]},
+		}
+	}
+	for _, name := range names {
+		delete(fields, name)
+	}
+	return jsonMarshal(fields)
+}
+
+// setTaggedField assigns value to the first field of the target struct whose `jsonapi`
+// struct tag matches the provided tag. Targets without such a field are left unchanged;
+// a tagged field whose type is not assignable from the value is reported as
+// [ErrInvalidTag].
+//
+// For example, resource links are captured by a field declared as:
+//
+//	Links map[string]jsonapi.Link `json:"-" jsonapi:"links"`
+func setTaggedField(target reflect.Value, tag string, value reflect.Value) error {
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return nil
+	}
+
+	target = target.Elem()
+	if target.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Type().Field(i)
+		if field.Tag.Get("jsonapi") != tag {
+			continue
+		}
+		if !value.Type().AssignableTo(field.Type) {
+			return fmt.Errorf("field %s tagged %q must be of type %s: %w", field.Name, tag, value.Type(), ErrInvalidTag)
+		}
+		if fieldValue := target.Field(i); fieldValue.CanSet() {
+			fieldValue.Set(value)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// typesMatch reports whether the expected and actual resource types are equal,
+// ignoring case if configured with [WithCaseInsensitiveTypes].
+func (o *options) typesMatch(expected, actual string) bool {
+	if o.foldTypes {
+		return strings.EqualFold(expected, actual)
+	}
+	return expected == actual
+}
+
+// allocEmbedded allocates nil embedded struct pointers on the target so that
+// unmarshaler methods promoted from embedded structs can be called safely.
+func allocEmbedded(target reflect.Value) {
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return
+	}
+
+	value := target.Elem()
+	if value.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.Anonymous || field.Type.Kind() != reflect.Ptr || field.Type.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		if fieldValue := value.Field(i); fieldValue.IsNil() && fieldValue.CanSet() {
+			fieldValue.Set(reflect.New(field.Type.Elem()))
+		}
+		allocEmbedded(value.Field(i))
+	}
+}
+
 // UnmarshalRef extracts relationship data from a JSON:API [Document] and populates
 // the target's specified [Relationship]. This is useful for relationship endpoint
 // operations like PATCH /resources/1/relationships/tags.
@@ -182,6 +491,44 @@ func UnmarshalRef(data []byte, name string, target RelationshipUnmarshaler, opts
 		return err
 	}
 
+	return unmarshalRefDocument(doc, name, target, &options)
+}
+
+// UnmarshalRefReplace extracts relationship data from a JSON:API [Document] and replaces
+// the target's specified [Relationship] with it. Unlike [UnmarshalRef], which adds each
+// identifier to the relationship, the target's [RelationshipClearer.ClearRef] method is
+// called first, giving PATCH /resources/1/relationships/tags full replacement semantics.
+// The relationship is only cleared if the document is valid for the relationship.
+func UnmarshalRefReplace(data []byte, name string, target RelationshipClearer, opts ...Options) error {
+	options := applyOptions(opts)
+
+	doc := &Document{}
+	err := jsonUnmarshal(data, doc)
+	if err != nil {
+		return err
+	}
+
+	if err := validateRefDocument(doc, name, target); err != nil {
+		return err
+	}
+
+	if err := target.ClearRef(name); errors.Is(err, ErrReadOnly) {
+		return readOnlyError(name, "/data", err)
+	} else if err != nil {
+		return fmt.Errorf("clear relationship %q: %w", name, err)
+	}
+
+	if err := unmarshalRefData(doc, name, target, &options); errors.Is(err, ErrReadOnly) {
+		return readOnlyError(name, "/data", err)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateRefDocument checks that the relationship exists on the target
+// and that the document data is valid for the relationship type.
+func validateRefDocument(doc *Document, name string, target RelationshipUnmarshaler) error {
 	var (
 		relationships   = target.Relationships()
 		relType, exists = relationships[name]
@@ -191,12 +538,33 @@ func UnmarshalRef(data []byte, name string, target RelationshipUnmarshaler, opts
 		return fmt.Errorf("relationship %q not found for resource %q", name, target.ResourceType())
 	}
 
-	if doc.Data == nil {
-		// Check if this is a to-many relationship - null is not allowed for to-many
-		if relType == RelationToMany {
-			return fmt.Errorf("null data not allowed for to-many relationship %q: use empty array instead", name)
-		}
+	// Check if this is a to-many relationship - null is not allowed for to-many
+	if doc.Data == nil && relType == RelationToMany {
+		return fmt.Errorf("null data not allowed for to-many relationship %q: use empty array instead", name)
+	}
+
+	return nil
+}
 
+// unmarshalRefDocument populates the target's specified relationship from the
+// primary data of a relationship document. [ErrReadOnly] failures are reported as a
+// 403 Forbidden [Error] pointing at the document's primary data.
+func unmarshalRefDocument(doc *Document, name string, target RelationshipUnmarshaler, options *options) error {
+	if err := validateRefDocument(doc, name, target); err != nil {
+		return err
+	}
+	if err := unmarshalRefData(doc, name, target, options); errors.Is(err, ErrReadOnly) {
+		return readOnlyError(name, "/data", err)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// unmarshalRefData populates the target's specified relationship from the validated
+// primary data of a relationship document.
+func unmarshalRefData(doc *Document, name string, target RelationshipUnmarshaler, options *options) error {
+	if doc.Data == nil {
 		// Handle null data by clearing the to-one relationship
 		return target.UnmarshalRef(name, "", nil)
 	}
@@ -216,7 +584,36 @@ func UnmarshalRef(data []byte, name string, target RelationshipUnmarshaler, opts
 
 	rel := &Relationship{Data: relData}
 
-	return unmarshalRelationship(rel, name, target, &options)
+	return unmarshalRelationship(rel, name, target, options)
+}
+
+// UnmarshalIdentifiers parses a JSON:API relationship document and returns the resource
+// identifiers in its primary data as [Ref] values. A to-one document returns a single
+// identifier, a to-many document returns one identifier per element, and null data
+// returns an empty slice. This is useful for relationship endpoints that only need
+// the identifiers rather than a typed target.
+func UnmarshalIdentifiers(data []byte) ([]ResourceIdentifier, error) {
+	doc := &Document{}
+	if err := jsonUnmarshal(data, doc); err != nil {
+		return nil, err
+	}
+
+	refs := []ResourceIdentifier{}
+	if doc.Data == nil {
+		return refs, nil
+	}
+
+	if doc.Data.isMany {
+		for _, res := range doc.Data.many {
+			refs = append(refs, Ref{ID: res.ID, Type: res.Type, Meta: res.Meta})
+		}
+		return refs, nil
+	}
+
+	if one := doc.Data.one; one.ID != "" || one.Type != "" {
+		refs = append(refs, Ref{ID: one.ID, Type: one.Type, Meta: one.Meta})
+	}
+	return refs, nil
 }
 
 // unmarshalRelationship unmarshals a single relationship into the target resource.
@@ -1,10 +1,16 @@
 package jsonapi
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUnmarshalMany(t *testing.T) {
@@ -43,6 +49,30 @@ func TestDocument_UnmarshalData_Single(t *testing.T) {
 	assert.Equal(t, "test1", resource.Name)
 }
 
+func TestDocument_UnmarshalData_InspectThenBind(t *testing.T) {
+	data := []byte(`{
+		"meta": {"dryRun": true},
+		"data": {"type": "articles", "id": "1", "attributes": {"title": "Hello"}, "relationships": {
+			"author": {"data": {"type": "users", "id": "9"}}
+		}}
+	}`)
+
+	// decode the body once, e.g. in middleware
+	var doc Document
+	require.NoError(t, Unmarshal(data, &doc))
+	assert.Equal(t, true, doc.Meta["dryRun"])
+	assert.Equal(t, "articles", doc.Data.one.Type)
+
+	// then bind the parsed document without re-parsing the bytes
+	var article Article
+	require.NoError(t, doc.UnmarshalData(&article, WithTypeValidation()))
+	assert.Equal(t, "Hello", article.Title)
+	assert.Equal(t, "9", article.AuthorID)
+
+	var item typedAttributesResource
+	assert.ErrorIs(t, doc.UnmarshalData(&item, WithTypeValidation()), ErrTypeMismatch)
+}
+
 func TestDocument_UnmarshalData_Many(t *testing.T) {
 	jsonData := `{
 		"data": [
@@ -76,3 +106,785 @@ func TestDocument_UnmarshalData_EmptyCollection(t *testing.T) {
 	assert.NotNil(t, resources)
 	assert.Len(t, resources, 0)
 }
+
+// EmbeddedBase carries the identifier and relationships shared by embedding resources.
+type EmbeddedBase struct {
+	ID       string `json:"-"`
+	AuthorID string `json:"-"`
+}
+
+func (b EmbeddedBase) ResourceID() string   { return b.ID }
+func (b EmbeddedBase) ResourceType() string { return "posts" }
+
+func (b *EmbeddedBase) SetResourceID(id string) error {
+	b.ID = id
+	return nil
+}
+
+func (b EmbeddedBase) Relationships() map[string]RelationType {
+	return map[string]RelationType{"author": RelationToOne}
+}
+
+func (b EmbeddedBase) MarshalRef(name string) []ResourceIdentifier {
+	if name == "author" {
+		return OneRef(User{ID: b.AuthorID})
+	}
+	return nil
+}
+
+func (b *EmbeddedBase) UnmarshalRef(name, id string, meta map[string]interface{}) error {
+	if name == "author" {
+		b.AuthorID = id
+	}
+	return nil
+}
+
+func TestUnmarshal_EmbeddedRelationships(t *testing.T) {
+	jsonData := `{
+		"data": {
+			"type": "posts",
+			"id": "1",
+			"attributes": {"title": "Embedded"},
+			"relationships": {
+				"author": {"data": {"type": "users", "id": "42"}}
+			}
+		}
+	}`
+
+	t.Run("embedded struct", func(t *testing.T) {
+		var post struct {
+			EmbeddedBase
+			Title string `json:"title"`
+		}
+		err := Unmarshal([]byte(jsonData), &post)
+		assert.NoError(t, err)
+		assert.Equal(t, "1", post.ID)
+		assert.Equal(t, "Embedded", post.Title)
+		assert.Equal(t, "42", post.AuthorID)
+	})
+
+	t.Run("embedded pointer struct", func(t *testing.T) {
+		var post struct {
+			*EmbeddedBase
+			Title string `json:"title"`
+		}
+		err := Unmarshal([]byte(jsonData), &post)
+		assert.NoError(t, err)
+		if assert.NotNil(t, post.EmbeddedBase) {
+			assert.Equal(t, "1", post.ID)
+			assert.Equal(t, "42", post.AuthorID)
+		}
+		assert.Equal(t, "Embedded", post.Title)
+	})
+}
+
+func TestUnmarshalIdentifiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		jsonData string
+		expected []ResourceIdentifier
+	}{
+		{
+			name:     "to-one",
+			jsonData: `{"data": {"type": "users", "id": "1"}}`,
+			expected: []ResourceIdentifier{Ref{Type: "users", ID: "1"}},
+		},
+		{
+			name:     "to-many",
+			jsonData: `{"data": [{"type": "tags", "id": "1"}, {"type": "tags", "id": "2", "meta": {"primary": true}}]}`,
+			expected: []ResourceIdentifier{
+				Ref{Type: "tags", ID: "1"},
+				Ref{Type: "tags", ID: "2", Meta: map[string]interface{}{"primary": true}},
+			},
+		},
+		{
+			name:     "empty to-many",
+			jsonData: `{"data": []}`,
+			expected: []ResourceIdentifier{},
+		},
+		{
+			name:     "null to-one",
+			jsonData: `{"data": null}`,
+			expected: []ResourceIdentifier{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs, err := UnmarshalIdentifiers([]byte(tt.jsonData))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, refs)
+		})
+	}
+
+	t.Run("invalid json", func(t *testing.T) {
+		_, err := UnmarshalIdentifiers([]byte(`{`))
+		assert.Error(t, err)
+	})
+}
+
+type linksTaggedResource struct {
+	ID    string          `json:"-"`
+	Name  string          `json:"name"`
+	Links map[string]Link `json:"-" jsonapi:"links"`
+}
+
+func (r linksTaggedResource) ResourceID() string   { return r.ID }
+func (r linksTaggedResource) ResourceType() string { return "test" }
+func (r *linksTaggedResource) SetResourceID(id string) error {
+	r.ID = id
+	return nil
+}
+
+type invalidLinksTaggedResource struct {
+	ID    string            `json:"-"`
+	Links map[string]string `json:"-" jsonapi:"links"`
+}
+
+func (r invalidLinksTaggedResource) ResourceID() string   { return r.ID }
+func (r invalidLinksTaggedResource) ResourceType() string { return "test" }
+func (r *invalidLinksTaggedResource) SetResourceID(id string) error {
+	r.ID = id
+	return nil
+}
+
+func TestUnmarshal_LinksTaggedField(t *testing.T) {
+	t.Run("captures resource links", func(t *testing.T) {
+		jsonData := `{
+			"data": {
+				"type": "test",
+				"id": "1",
+				"attributes": {"name": "test1"},
+				"links": {"self": "http://example.com/test/1"}
+			}
+		}`
+
+		var resource linksTaggedResource
+		err := Unmarshal([]byte(jsonData), &resource)
+		assert.NoError(t, err)
+		assert.Equal(t, "test1", resource.Name)
+		assert.Equal(t, map[string]Link{"self": {Href: "http://example.com/test/1"}}, resource.Links)
+	})
+
+	t.Run("captures links for each collection element", func(t *testing.T) {
+		jsonData := `{
+			"data": [
+				{"type": "test", "id": "1", "links": {"self": "http://example.com/test/1"}},
+				{"type": "test", "id": "2", "links": {"self": "http://example.com/test/2"}}
+			]
+		}`
+
+		var resources []linksTaggedResource
+		err := Unmarshal([]byte(jsonData), &resources)
+		assert.NoError(t, err)
+		if assert.Len(t, resources, 2) {
+			assert.Equal(t, "http://example.com/test/1", resources[0].Links["self"].Href)
+			assert.Equal(t, "http://example.com/test/2", resources[1].Links["self"].Href)
+		}
+	})
+
+	t.Run("no links leaves field unset", func(t *testing.T) {
+		var resource linksTaggedResource
+		err := Unmarshal([]byte(`{"data": {"type": "test", "id": "1"}}`), &resource)
+		assert.NoError(t, err)
+		assert.Nil(t, resource.Links)
+	})
+}
+
+type bigNumberResource struct {
+	ID      string     `json:"-"`
+	Balance *big.Int   `json:"balance"`
+	Ratio   *big.Float `json:"ratio"`
+}
+
+func (r bigNumberResource) ResourceID() string   { return r.ID }
+func (r bigNumberResource) ResourceType() string { return "accounts" }
+func (r *bigNumberResource) SetResourceID(id string) error {
+	r.ID = id
+	return nil
+}
+
+func TestBigNumberAttributes_RoundTrip(t *testing.T) {
+	balance, ok := new(big.Int).SetString("1234567890123456789012345678901234567890", 10)
+	assert.True(t, ok)
+	ratio, _, err := big.ParseFloat("3.14159265358979323846264338327950288419", 10, 256, big.ToNearestEven)
+	assert.NoError(t, err)
+
+	original := bigNumberResource{ID: "1", Balance: balance, Ratio: ratio}
+
+	data, err := Marshal(original)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"balance":1234567890123456789012345678901234567890`)
+
+	// big.Float decodes with 64 bits of precision unless the target is preallocated.
+	decoded := bigNumberResource{Ratio: new(big.Float).SetPrec(256)}
+	err = Unmarshal(data, &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", decoded.ID)
+	if assert.NotNil(t, decoded.Balance) {
+		assert.Equal(t, 0, balance.Cmp(decoded.Balance))
+		assert.Equal(t, "1234567890123456789012345678901234567890", decoded.Balance.String())
+	}
+	if assert.NotNil(t, decoded.Ratio) {
+		assert.Equal(t, ratio.Text('g', 38), decoded.Ratio.Text('g', 38))
+	}
+}
+
+type typedAttributesResource struct {
+	ID        string  `json:"-"`
+	Count     int     `json:"count"`
+	Price     float64 `json:"price"`
+	Published bool    `json:"published"`
+	Label     string  `json:"label"`
+}
+
+func (r typedAttributesResource) ResourceID() string   { return r.ID }
+func (r typedAttributesResource) ResourceType() string { return "items" }
+func (r *typedAttributesResource) SetResourceID(id string) error {
+	r.ID = id
+	return nil
+}
+
+// Attributes are decoded by the configured JSON unmarshaler without any coercion,
+// so values that do not match the target field type are always rejected.
+func TestUnmarshal_AttributeTypesAreStrict(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes string
+		wantErr    bool
+	}{
+		{name: "matching types", attributes: `{"count": 1, "price": 1.5, "published": true, "label": "a"}`},
+		{name: "string into int", attributes: `{"count": "123"}`, wantErr: true},
+		{name: "float into int", attributes: `{"count": 1.5}`, wantErr: true},
+		{name: "string into bool", attributes: `{"published": "true"}`, wantErr: true},
+		{name: "number into string", attributes: `{"label": 123}`, wantErr: true},
+		{name: "bool into float", attributes: `{"price": true}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonData := `{"data": {"type": "items", "id": "1", "attributes": ` + tt.attributes + `}}`
+
+			var resource typedAttributesResource
+			err := Unmarshal([]byte(jsonData), &resource)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "unmarshal attributes")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_CaseInsensitiveTypes(t *testing.T) {
+	jsonData := `{"data": {"type": "TEST", "id": "1", "attributes": {"name": "test1"}}}`
+
+	t.Run("rejected with type validation", func(t *testing.T) {
+		var resource testResource
+		err := Unmarshal([]byte(jsonData), &resource, WithTypeValidation())
+		assert.ErrorContains(t, err, "resource type mismatch")
+	})
+
+	t.Run("accepted with case insensitive types", func(t *testing.T) {
+		var resource testResource
+		err := Unmarshal([]byte(jsonData), &resource, WithTypeValidation(), WithCaseInsensitiveTypes())
+		assert.NoError(t, err)
+		assert.Equal(t, "1", resource.ID)
+		assert.Equal(t, "test1", resource.Name)
+	})
+
+	t.Run("different types still rejected", func(t *testing.T) {
+		var resource testResource
+		err := Unmarshal([]byte(`{"data": {"type": "users", "id": "1"}}`), &resource,
+			WithTypeValidation(), WithCaseInsensitiveTypes())
+		assert.ErrorContains(t, err, "resource type mismatch")
+	})
+}
+
+// replaceableArticle supports full replacement of its relationships.
+type replaceableArticle struct {
+	Article
+}
+
+func (a *replaceableArticle) ClearRef(name string) error {
+	switch name {
+	case "author":
+		a.AuthorID = ""
+	case "tags":
+		a.TagIDs = nil
+	}
+	return nil
+}
+
+func TestUnmarshalRefReplace(t *testing.T) {
+	body := `{"data": [{"type": "tags", "id": "3"}, {"type": "tags", "id": "4"}]}`
+
+	t.Run("UnmarshalRef appends", func(t *testing.T) {
+		article := replaceableArticle{Article{ID: "1", TagIDs: []string{"1", "2"}}}
+		err := UnmarshalRef([]byte(body), "tags", &article)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1", "2", "3", "4"}, article.TagIDs)
+	})
+
+	t.Run("UnmarshalRefReplace replaces", func(t *testing.T) {
+		article := replaceableArticle{Article{ID: "1", TagIDs: []string{"1", "2"}}}
+		err := UnmarshalRefReplace([]byte(body), "tags", &article)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"3", "4"}, article.TagIDs)
+	})
+
+	t.Run("empty array clears", func(t *testing.T) {
+		article := replaceableArticle{Article{ID: "1", TagIDs: []string{"1", "2"}}}
+		err := UnmarshalRefReplace([]byte(`{"data": []}`), "tags", &article)
+		assert.NoError(t, err)
+		assert.Empty(t, article.TagIDs)
+	})
+
+	t.Run("invalid document does not clear", func(t *testing.T) {
+		article := replaceableArticle{Article{ID: "1", TagIDs: []string{"1", "2"}}}
+		err := UnmarshalRefReplace([]byte(`{"data": null}`), "tags", &article)
+		assert.ErrorContains(t, err, "null data not allowed")
+		assert.Equal(t, []string{"1", "2"}, article.TagIDs)
+	})
+
+	t.Run("unknown relationship", func(t *testing.T) {
+		article := replaceableArticle{Article{ID: "1"}}
+		err := UnmarshalRefReplace([]byte(body), "unknown", &article)
+		assert.ErrorContains(t, err, "not found")
+	})
+
+	t.Run("from request body", func(t *testing.T) {
+		article := replaceableArticle{Article{ID: "1", TagIDs: []string{"1"}}}
+		err := (&Context{}).UnmarshalRefReplace(strings.NewReader(body), "tags", &article)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"3", "4"}, article.TagIDs)
+	})
+}
+
+func TestUnmarshalAs(t *testing.T) {
+	t.Run("single resource", func(t *testing.T) {
+		resource, err := UnmarshalAs[testResource]([]byte(`{"data": {"type": "test", "id": "1", "attributes": {"name": "test1"}}}`))
+		assert.NoError(t, err)
+		assert.Equal(t, testResource{ID: "1", Name: "test1"}, resource)
+	})
+
+	t.Run("collection into single resource", func(t *testing.T) {
+		_, err := UnmarshalAs[testResource]([]byte(`{"data": [{"type": "test", "id": "1"}]}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("with options", func(t *testing.T) {
+		_, err := UnmarshalAs[testResource]([]byte(`{"data": {"type": "users", "id": "1"}}`), WithTypeValidation())
+		assert.ErrorContains(t, err, "resource type mismatch")
+	})
+}
+
+func TestUnmarshalManyAs(t *testing.T) {
+	t.Run("collection", func(t *testing.T) {
+		resources, err := UnmarshalManyAs[testResource]([]byte(`{
+			"data": [
+				{"type": "test", "id": "1", "attributes": {"name": "test1"}},
+				{"type": "test", "id": "2", "attributes": {"name": "test2"}}
+			]
+		}`))
+		assert.NoError(t, err)
+		assert.Equal(t, []testResource{{ID: "1", Name: "test1"}, {ID: "2", Name: "test2"}}, resources)
+	})
+
+	t.Run("empty collection", func(t *testing.T) {
+		resources, err := UnmarshalManyAs[testResource]([]byte(`{"data": []}`))
+		assert.NoError(t, err)
+		assert.Empty(t, resources)
+	})
+
+	t.Run("single resource into collection", func(t *testing.T) {
+		_, err := UnmarshalManyAs[testResource]([]byte(`{"data": {"type": "test", "id": "1"}}`))
+		assert.Error(t, err)
+	})
+}
+
+// readOnlyAuthorPost rejects client writes to its author relationship.
+type readOnlyAuthorPost struct {
+	EmbeddedBase
+}
+
+func (p *readOnlyAuthorPost) UnmarshalRef(name, id string, meta map[string]interface{}) error {
+	if name == "author" {
+		return ErrReadOnly
+	}
+	return p.EmbeddedBase.UnmarshalRef(name, id, meta)
+}
+
+func TestUnmarshal_ReadOnlyRelationship(t *testing.T) {
+	jsonData := `{
+		"data": {
+			"type": "posts",
+			"id": "1",
+			"relationships": {
+				"author": {"data": {"type": "users", "id": "42"}}
+			}
+		}
+	}`
+
+	var post readOnlyAuthorPost
+	err := Unmarshal([]byte(jsonData), &post)
+	assert.ErrorIs(t, err, ErrReadOnly)
+
+	var jsonapiErr *Error
+	if assert.ErrorAs(t, err, &jsonapiErr) {
+		assert.Equal(t, "403", jsonapiErr.Status)
+		assert.Equal(t, "/data/relationships/author", jsonapiErr.Source.Pointer)
+	}
+	assert.Empty(t, post.AuthorID)
+
+	t.Run("relationship endpoint", func(t *testing.T) {
+		for name, data := range map[string]string{
+			"linkage": `{"data": {"type": "users", "id": "42"}}`,
+			"null":    `{"data": null}`,
+		} {
+			var post readOnlyAuthorPost
+			err := UnmarshalRef([]byte(data), "author", &post)
+			assert.ErrorIs(t, err, ErrReadOnly, name)
+
+			var jsonapiErr *Error
+			if assert.ErrorAs(t, err, &jsonapiErr, name) {
+				assert.Equal(t, "403", jsonapiErr.Status)
+				assert.Equal(t, "/data", jsonapiErr.Source.Pointer)
+			}
+		}
+	})
+}
+
+func TestUnmarshal_StrictIdentifiers(t *testing.T) {
+	conflict := `{"data": {"type": "articles", "id": "1", "lid": "temp-1", "attributes": {"title": "Draft"}}}`
+
+	t.Run("rejects id and lid", func(t *testing.T) {
+		var article Article
+		err := Unmarshal([]byte(conflict), &article, WithStrictIdentifiers())
+		assert.EqualError(t, err, `resource "1" declares both id and lid "temp-1"`)
+	})
+
+	t.Run("rejects conflicts within collections", func(t *testing.T) {
+		var articles []Article
+		err := Unmarshal([]byte(`{"data": [{"type": "articles", "id": "1", "lid": "a"}]}`), &articles, WithStrictIdentifiers())
+		assert.ErrorContains(t, err, "declares both id and lid")
+	})
+
+	t.Run("allows lid alone", func(t *testing.T) {
+		var article Article
+		err := Unmarshal([]byte(`{"data": {"type": "articles", "lid": "temp-1", "attributes": {"title": "Draft"}}}`), &article, WithStrictIdentifiers())
+		assert.NoError(t, err)
+		assert.Equal(t, "Draft", article.Title)
+	})
+
+	t.Run("lenient by default", func(t *testing.T) {
+		var article Article
+		assert.NoError(t, Unmarshal([]byte(conflict), &article))
+		assert.Equal(t, "1", article.ID)
+	})
+}
+
+func TestUnmarshal_SingleFromArray(t *testing.T) {
+	one := `{"data": [{"type": "articles", "id": "1", "attributes": {"title": "Only"}}]}`
+	two := `{"data": [{"type": "articles", "id": "1"}, {"type": "articles", "id": "2"}]}`
+
+	t.Run("strict by default", func(t *testing.T) {
+		var article Article
+		err := Unmarshal([]byte(one), &article)
+		assert.EqualError(t, err, "cannot unmarshal array to struct target jsonapi.Article")
+	})
+
+	t.Run("one element array", func(t *testing.T) {
+		var article Article
+		assert.NoError(t, Unmarshal([]byte(one), &article, WithSingleFromArray()))
+		assert.Equal(t, "1", article.ID)
+		assert.Equal(t, "Only", article.Title)
+	})
+
+	t.Run("multi element array", func(t *testing.T) {
+		var article Article
+		err := Unmarshal([]byte(two), &article, WithSingleFromArray())
+		assert.EqualError(t, err, "cannot unmarshal array to struct target jsonapi.Article")
+	})
+
+	t.Run("slice targets are unaffected", func(t *testing.T) {
+		var articles []Article
+		assert.NoError(t, Unmarshal([]byte(two), &articles, WithSingleFromArray()))
+		assert.Len(t, articles, 2)
+	})
+}
+
+func TestUnmarshal_CollectErrors(t *testing.T) {
+	single := `{"data": {"type": "items", "id": "1", "attributes": {"count": "many", "price": "cheap", "label": "Widget", "published": 1}}}`
+
+	t.Run("fails fast by default", func(t *testing.T) {
+		var item typedAttributesResource
+		err := Unmarshal([]byte(single), &item)
+		assert.ErrorContains(t, err, "unmarshal attributes")
+
+		var errs MultiError
+		assert.False(t, errors.As(err, &errs))
+	})
+
+	t.Run("collects every bad attribute", func(t *testing.T) {
+		var item typedAttributesResource
+		err := Unmarshal([]byte(single), &item, WithCollectErrors())
+
+		var errs MultiError
+		if assert.ErrorAs(t, err, &errs) && assert.Len(t, errs, 3) {
+			assert.Equal(t, "/data/attributes/count", errs[0].Source.Pointer)
+			assert.Equal(t, "/data/attributes/price", errs[1].Source.Pointer)
+			assert.Equal(t, "/data/attributes/published", errs[2].Source.Pointer)
+			assert.Equal(t, "422", errs[0].Status)
+		}
+		assert.Equal(t, "Widget", item.Label)
+	})
+
+	t.Run("collection pointers include the index", func(t *testing.T) {
+		many := `{"data": [
+			{"type": "items", "id": "1", "attributes": {"count": 1}},
+			{"type": "items", "id": "2", "attributes": {"count": "two"}}
+		]}`
+
+		var items []typedAttributesResource
+		err := Unmarshal([]byte(many), &items, WithCollectErrors())
+
+		var errs MultiError
+		if assert.ErrorAs(t, err, &errs) && assert.Len(t, errs, 1) {
+			assert.Equal(t, "/data/1/attributes/count", errs[0].Source.Pointer)
+		}
+	})
+
+	t.Run("collects errors from every element", func(t *testing.T) {
+		many := `{"data": [
+			{"type": "items", "id": "1", "attributes": {"count": "one"}},
+			{"type": "items", "id": "2", "attributes": {"count": 2}},
+			{"type": "items", "id": "3", "attributes": {"count": "three", "price": "cheap"}}
+		]}`
+
+		var items []typedAttributesResource
+		err := Unmarshal([]byte(many), &items, WithCollectErrors())
+
+		var errs MultiError
+		if assert.ErrorAs(t, err, &errs) && assert.Len(t, errs, 3) {
+			assert.Equal(t, "/data/0/attributes/count", errs[0].Source.Pointer)
+			assert.Equal(t, "/data/2/attributes/count", errs[1].Source.Pointer)
+			assert.Equal(t, "/data/2/attributes/price", errs[2].Source.Pointer)
+		}
+		assert.Empty(t, items)
+	})
+}
+
+func TestUnmarshal_InterfaceTarget(t *testing.T) {
+	registry := WithTypeRegistry(TypeRegistry{
+		"articles": func() ResourceUnmarshaler { return &Article{} },
+		"items":    func() ResourceUnmarshaler { return &typedAttributesResource{} },
+	})
+
+	t.Run("single resource", func(t *testing.T) {
+		var out ResourceIdentifier
+		err := Unmarshal([]byte(`{"data": {"type": "articles", "id": "1", "attributes": {"title": "Hello"}}}`), &out, registry)
+		assert.NoError(t, err)
+		assert.Equal(t, &Article{ID: "1", Title: "Hello"}, out)
+	})
+
+	t.Run("mixed collection", func(t *testing.T) {
+		var out []ResourceIdentifier
+		err := Unmarshal([]byte(`{"data": [
+			{"type": "articles", "id": "1", "attributes": {"title": "Hello"}},
+			{"type": "items", "id": "2", "attributes": {"count": 3}}
+		]}`), &out, registry)
+		assert.NoError(t, err)
+		assert.Equal(t, []ResourceIdentifier{
+			&Article{ID: "1", Title: "Hello"},
+			&typedAttributesResource{ID: "2", Count: 3},
+		}, out)
+	})
+
+	t.Run("unregistered type", func(t *testing.T) {
+		var out ResourceIdentifier
+		err := Unmarshal([]byte(`{"data": {"type": "videos", "id": "1"}}`), &out, registry)
+		assert.EqualError(t, err, `no type registered for resource type "videos"`)
+	})
+
+	t.Run("registered type does not implement target", func(t *testing.T) {
+		var out interface{ Publish() }
+		err := Unmarshal([]byte(`{"data": {"type": "articles", "id": "1"}}`), &out, registry)
+		assert.ErrorContains(t, err, `registered type *jsonapi.Article for resource type "articles" does not implement`)
+	})
+}
+
+func TestUnmarshal_TypeMismatchHandler(t *testing.T) {
+	data := []byte(`{"data": [
+		{"type": "posts", "id": "1", "attributes": {"title": "Post"}},
+		{"type": "articles", "id": "2", "attributes": {"title": "Article"}}
+	]}`)
+
+	var mismatches [][2]string
+	handler := WithTypeMismatchHandler(func(expected, got string) {
+		mismatches = append(mismatches, [2]string{expected, got})
+	})
+
+	t.Run("lenient mode reports mismatches", func(t *testing.T) {
+		mismatches = nil
+		var articles []Article
+		assert.NoError(t, Unmarshal(data, &articles, handler))
+		assert.Len(t, articles, 2)
+		assert.Equal(t, [][2]string{{"articles", "posts"}}, mismatches)
+	})
+
+	t.Run("type validation fails instead", func(t *testing.T) {
+		mismatches = nil
+		var articles []Article
+		assert.Error(t, Unmarshal(data, &articles, handler, WithTypeValidation()))
+		assert.Empty(t, mismatches)
+	})
+}
+
+// validatedArticle is an [Article] that requires a title.
+type validatedArticle struct {
+	Article
+}
+
+func (a *validatedArticle) ValidateJSONAPIResource(ctx context.Context) error {
+	if record, ok := ctx.Value(validationRecorderKey{}).(func(id string)); ok {
+		record(a.ID)
+	}
+	if a.Title == "" {
+		return errors.New("title is required")
+	}
+	return nil
+}
+
+// validationRecorderKey is the context key of a func(id string) that validatedArticle
+// calls with its ID when validated.
+type validationRecorderKey struct{}
+
+// withValidationRecorder returns a context that records the IDs of validated resources in ids.
+func withValidationRecorder(ids *[]string) context.Context {
+	return context.WithValue(context.Background(), validationRecorderKey{}, func(id string) {
+		*ids = append(*ids, id)
+	})
+}
+
+func TestUnmarshal_ResourceValidator(t *testing.T) {
+	t.Run("valid resource", func(t *testing.T) {
+		var article validatedArticle
+		err := Unmarshal([]byte(`{"data": {"type": "articles", "id": "1", "attributes": {"title": "Hello"}}}`), &article)
+		assert.NoError(t, err)
+		assert.Equal(t, "Hello", article.Title)
+	})
+
+	t.Run("invalid resource", func(t *testing.T) {
+		var article validatedArticle
+		err := Unmarshal([]byte(`{"data": {"type": "articles", "id": "1", "attributes": {"content": "body"}}}`), &article)
+		assert.ErrorContains(t, err, `validate resource articles "1": title is required`)
+	})
+
+	t.Run("stops at first invalid element", func(t *testing.T) {
+		var validated []string
+		data := []byte(`{"data": [
+			{"type": "articles", "id": "1", "attributes": {"title": "First"}},
+			{"type": "articles", "id": "2", "attributes": {}},
+			{"type": "articles", "id": "3", "attributes": {"title": "Third"}}
+		]}`)
+
+		var articles []validatedArticle
+		err := Unmarshal(data, &articles, WithValidationContext(withValidationRecorder(&validated)))
+		assert.ErrorContains(t, err, "unmarshal resource 1")
+		assert.ErrorContains(t, err, "title is required")
+		assert.Equal(t, []string{"1", "2"}, validated)
+		assert.Empty(t, articles)
+	})
+
+	t.Run("receives validation context", func(t *testing.T) {
+		var validated []string
+		var article validatedArticle
+		err := Unmarshal([]byte(`{"data": {"type": "articles", "id": "1", "attributes": {"title": "Hello"}}}`), &article,
+			WithValidationContext(withValidationRecorder(&validated)))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1"}, validated)
+	})
+
+	t.Run("Context.Unmarshal with the request context", func(t *testing.T) {
+		var validated []string
+		req := httptest.NewRequest("POST", "/articles", strings.NewReader(`{"data": {"type": "articles", "id": "1", "attributes": {"title": "Hello"}}}`))
+		req = req.WithContext(WithContext(withValidationRecorder(&validated), &Context{ResourceType: "articles"}))
+
+		var article validatedArticle
+		require.NoError(t, FromContext(req.Context()).Unmarshal(req.Body, &article, WithValidationContext(req.Context())))
+		assert.Equal(t, []string{"1"}, validated)
+	})
+}
+
+func TestUnmarshal_AllowedAttributes(t *testing.T) {
+	data := []byte(`{"data": {"type": "articles", "id": "1", "attributes": {"title": "Hello", "content": "injected"}}}`)
+	allow := WithAllowedAttributes("articles", "title")
+
+	t.Run("ignores disallowed attributes", func(t *testing.T) {
+		var article Article
+		assert.NoError(t, Unmarshal(data, &article, allow))
+		assert.Equal(t, "Hello", article.Title)
+		assert.Empty(t, article.Content)
+	})
+
+	t.Run("rejects disallowed attributes in strict mode", func(t *testing.T) {
+		var article Article
+		err := Unmarshal(data, &article, allow, WithStrictAttributes())
+
+		var apiErr *Error
+		if assert.ErrorAs(t, err, &apiErr) {
+			assert.Equal(t, "403", apiErr.Status)
+			assert.Equal(t, "/data/attributes/content", apiErr.Source.Pointer)
+		}
+	})
+
+	t.Run("other resource types accept all attributes", func(t *testing.T) {
+		var article Article
+		assert.NoError(t, Unmarshal(data, &article, WithAllowedAttributes("users", "name"), WithStrictAttributes()))
+		assert.Equal(t, "injected", article.Content)
+	})
+
+	t.Run("allow list follows the target type", func(t *testing.T) {
+		for _, sent := range []string{"posts", "ARTICLES"} {
+			data := []byte(`{"data": {"type": "` + sent + `", "id": "1", "attributes": {"title": "Hello", "content": "injected"}}}`)
+
+			var article Article
+			err := Unmarshal(data, &article, allow, WithStrictAttributes(), WithCaseInsensitiveTypes())
+			assert.Error(t, err, sent)
+			assert.Empty(t, article.Content, sent)
+		}
+	})
+}
+
+func TestUnmarshal_SentinelErrors(t *testing.T) {
+	single := []byte(`{"data": {"type": "articles", "id": "1"}}`)
+	many := []byte(`{"data": [{"type": "articles", "id": "1"}]}`)
+
+	t.Run("nil target", func(t *testing.T) {
+		assert.ErrorIs(t, Unmarshal(single, nil), ErrNilValue)
+	})
+
+	t.Run("non-pointer target", func(t *testing.T) {
+		assert.ErrorIs(t, Unmarshal(single, Article{}), ErrNotPointer)
+		assert.ErrorIs(t, Unmarshal(many, []Article{}), ErrNotPointer)
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		var articles []Article
+		err := Unmarshal([]byte(`{"data": [{"type": "users", "id": "1"}]}`), &articles, WithTypeValidation())
+		assert.ErrorIs(t, err, ErrTypeMismatch)
+	})
+
+	t.Run("non-pointer id target", func(t *testing.T) {
+		assert.ErrorIs(t, ParseID("1", 1), ErrNotPointer)
+	})
+
+	t.Run("invalid links tag", func(t *testing.T) {
+		var resource invalidLinksTaggedResource
+		data := []byte(`{"data": {"type": "test", "id": "1", "links": {"self": "http://example.com/test/1"}}}`)
+		err := Unmarshal(data, &resource)
+		assert.ErrorIs(t, err, ErrInvalidTag)
+		assert.Contains(t, err.Error(), "field Links")
+	})
+}